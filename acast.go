@@ -27,20 +27,28 @@ package main
  */
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"image"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
 // Main RadioSpiral
 const STATIONS_QUERY_URL = "https://spiral.radio/api/stations"
 const NOWPLAYING_URL = "https://radiospiral.radio/api/nowplaying/"
+const SCHEDULE_URL = "https://radiospiral.radio/api/station/%s/schedule"
 
 const REMOVE_TEST_STATION = "rstest"
 
+// Header AzuraCast takes API keys in
+const API_KEY_HEADER = "X-API-Key"
+
 type StationInfo struct {
 	Id              int    `json:"id"`
 	Name            string `json:"name"`
@@ -57,33 +65,50 @@ type StationInfo struct {
 	IsPublic        bool   `json:"is_public"`
 }
 
-// JSON data we receive from the wp-json/radio/broadcast endpoint
+// Some servers send their numbers as strings ("180" instead of 180), we take
+// both. Anything else that isn't a number counts as 0, like a missing field,
+// so one odd field doesn't cost us the whole response.
+type FlexInt int64
+
+func (flex *FlexInt) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseFloat(strings.Trim(string(data), `"`), 64)
+	if err != nil {
+		*flex = 0
+		return nil
+	}
+	*flex = FlexInt(value)
+	return nil
+}
+
+// JSON data we receive from the schedule endpoint, one per scheduled show
 type BroadcastResponse struct {
-	Type        string `json:"type"`
-	Name        string `json:"name"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	StartTime   int64  `json:"start_timestamp"`
-	IsNow       bool   `json:"is_now"`
+	Type        string  `json:"type"`
+	Name        string  `json:"name"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	StartTime   FlexInt `json:"start_timestamp"`
+	IsNow       bool    `json:"is_now"`
 }
 
 type StationResponse struct {
-	NowPlaying NowPlayingInfo `json:"now_playing"`
-	Listeners  ListenersInfo  `json:"listeners"`
-	Live       LiveInfo       `json:"live"`
+	Station     StationInfo     `json:"station"`
+	NowPlaying  NowPlayingInfo  `json:"now_playing"`
+	PlayingNext PlayingNextInfo `json:"playing_next"`
+	Listeners   ListenersInfo   `json:"listeners"`
+	Live        LiveInfo        `json:"live"`
 }
 
 type ListenersInfo struct {
-	Total   int `json:"total"`
-	Unique  int `json:"unique"`
-	Current int `json:"current"`
+	Total   FlexInt `json:"total"`
+	Unique  FlexInt `json:"unique"`
+	Current FlexInt `json:"current"`
 }
 
 type LiveInfo struct {
-	IsLive         bool   `json:"is_live"`
-	StreamerName   string `json:"streamer_name"`
-	BroadcastStart string `json:"broadcast_start"`
-	Art            string `json:"art"`
+	IsLive         bool    `json:"is_live"`
+	StreamerName   string  `json:"streamer_name"`
+	BroadcastStart FlexInt `json:"broadcast_start"`
+	Art            string  `json:"art"`
 }
 
 type SongInfo struct {
@@ -99,33 +124,99 @@ type SongInfo struct {
 }
 
 type NowPlayingInfo struct {
-	ShId      int      `json:"sh_id"`
-	PlayedAt  int64    `json:"played_at"`
-	Duration  int      `json:"duration"`
+	ShId      FlexInt  `json:"sh_id"`
+	PlayedAt  FlexInt  `json:"played_at"`
+	Duration  FlexInt  `json:"duration"`
 	Playlist  string   `json:"playlist"`
 	Streamer  string   `json:"streamer"`
 	IsRequest bool     `json:"is_request"`
 	Song      SongInfo `json:"song"`
-	Elapsed   int      `json:"elapsed"`
-	Remaining int      `json:"remaining"`
+	Elapsed   FlexInt  `json:"elapsed"`
+	Remaining FlexInt  `json:"remaining"`
+}
+
+// The track queued to play after the current one
+type PlayingNextInfo struct {
+	CuedAt    FlexInt  `json:"cued_at"`
+	PlayedAt  FlexInt  `json:"played_at"`
+	Duration  FlexInt  `json:"duration"`
+	Playlist  string   `json:"playlist"`
+	IsRequest bool     `json:"is_request"`
+	Song      SongInfo `json:"song"`
 }
 
-// Load images from URLs
-func loadImageURL(url string) image.Image {
+// A message from the station, the id changes with each new one
+type Announcement struct {
+	Id      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// The art server answered, but not with the image
+type ArtStatusError struct {
+	URL    string
+	Status string
+	Code   int
+}
+
+func (statusErr *ArtStatusError) Error() string {
+	return fmt.Sprintf("fetching %s: %s", statusErr.URL, statusErr.Status)
+}
+
+// Load images from URLs, giving up when the context is done
+func loadImageURL(ctx context.Context, url string) (image.Image, error) {
 	parts := strings.Split(url, "?")
-	resp, err := http.Get(parts[0])
-	check(err)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parts[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := artClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
 
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ArtStatusError{URL: parts[0], Status: resp.Status, Code: resp.StatusCode}
+	}
+
 	img, _, err := image.Decode(resp.Body)
-	check(err)
-	return img
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// The art for what's on: the one of the live show while there's one, or else
+// the one of the track. While live that's the streamer's, and tracks without
+// art get the station's default one. Empty when there's no art at all, so we
+// show our placeholder.
+func selectArtURL(stationData *StationResponse) string {
+	if stationData == nil {
+		return ""
+	}
+	if stationData.Live.IsLive && stationData.Live.Art != "" {
+		return stationData.Live.Art
+	}
+	return stationData.NowPlaying.Song.Art
+}
+
+// GET against the API, with the API key if we have one. Public stations don't
+// need it, but private ones or mirrors may.
+func apiGet(apiEndpoint string, apiKey string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, apiEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set(API_KEY_HEADER, apiKey)
+	}
+	return apiClient.Do(req)
 }
 
 // Query the station info
-func queryStation(station StationInfo) (*StationResponse, error) {
+func queryStation(station StationInfo, apiKey string) (*StationResponse, error) {
 	apiEndpoint := NOWPLAYING_URL + station.Shortcode
-	resp, err := http.Get(apiEndpoint)
+	resp, err := apiGet(apiEndpoint, apiKey)
 	if err != nil {
 		// If we get an error fetching the data, await a minute and retry
 		log.Println("[ERROR] Error when querying broadcast endpoint")
@@ -143,15 +234,125 @@ func queryStation(station StationInfo) (*StationResponse, error) {
 		return nil, err
 	}
 
+	response, err := decodeStationResponse(body, station.Shortcode)
+	if err != nil {
+		log.Println("[ERROR] Unexpected now playing data")
+		log.Println(err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// The now playing endpoint gives us a single object when asked for one station,
+// but AzuraCast can also answer with an array, one element per station or mount.
+// In that case we pick the one matching the shortcode (or the first one if there's
+// no shortcode to match).
+func decodeStationResponse(body []byte, shortcode string) (*StationResponse, error) {
 	var response StationResponse
-	json.Unmarshal(body, &response)
+	err := json.Unmarshal(body, &response)
+	if err == nil {
+		return &response, nil
+	}
 
-	return &response, nil
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "" {
+		return nil, err
+	}
+
+	var responses []StationResponse
+	err = json.Unmarshal(body, &responses)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range responses {
+		if shortcode == "" || responses[i].Station.Shortcode == shortcode {
+			return &responses[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no now playing data for station %q", shortcode)
+}
+
+// Query the upcoming shows of the station
+func querySchedule(station StationInfo, apiKey string) ([]BroadcastResponse, error) {
+	apiEndpoint := fmt.Sprintf(SCHEDULE_URL, station.Shortcode)
+	resp, err := apiGet(apiEndpoint, apiKey)
+	if err != nil {
+		log.Println("[ERROR] Error when querying schedule endpoint")
+		log.Println(err)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	defer resp.Body.Close()
+
+	if err != nil {
+		log.Println("[ERROR] Error when reading the body")
+		log.Println(err)
+		return nil, err
+	}
+
+	var response []BroadcastResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		log.Println("[ERROR] Unexpected schedule data")
+		log.Println(err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Query the current announcement at the URL, nil if there's none. AzuraCast
+// has nothing like it, it's a small JSON file the station can publish when it
+// has something to tell its listeners.
+func queryAnnouncement(announcementURL string, apiKey string) (*Announcement, error) {
+	resp, err := apiGet(announcementURL, apiKey)
+	if err != nil {
+		log.Println("[ERROR] Error when querying the announcement")
+		log.Println(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("[ERROR] Error when reading the body")
+		log.Println(err)
+		return nil, err
+	}
+
+	return decodeAnnouncement(body)
+}
+
+func decodeAnnouncement(body []byte) (*Announcement, error) {
+	var announcement Announcement
+	err := json.Unmarshal(body, &announcement)
+	if err != nil {
+		log.Println("[ERROR] Unexpected announcement data")
+		log.Println(err)
+		return nil, err
+	}
+
+	if strings.TrimSpace(announcement.Message) == "" {
+		return nil, nil
+	}
+	// Without an id the message itself tells them apart
+	if announcement.Id == "" {
+		announcement.Id = announcement.Message
+	}
+	return &announcement, nil
 }
 
 // Query the stations available
 func fetchStations() ([]StationInfo, error) {
-	resp, err := http.Get(STATIONS_QUERY_URL)
+	resp, err := apiClient.Get(STATIONS_QUERY_URL)
 	if err != nil {
 		// If we get an error fetching the data, await a minute and retry
 		log.Println("[ERROR] Error when querying available stations")
@@ -170,7 +371,12 @@ func fetchStations() ([]StationInfo, error) {
 	}
 
 	var response []StationInfo
-	json.Unmarshal(body, &response)
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		log.Println("[ERROR] Unexpected stations data")
+		log.Println(err)
+		return nil, err
+	}
 
 	stations := make([]StationInfo, 0)
 	for _, elem := range response {
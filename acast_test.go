@@ -0,0 +1,212 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeStationResponse(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		shortcode string
+		// What we expect out of it
+		station   string
+		song      SongInfo
+		live      LiveInfo
+		listeners ListenersInfo
+		duration  FlexInt
+		elapsed   FlexInt
+		nextSong  string
+	}{
+		{
+			fixture:   "nowplaying.json",
+			shortcode: "radiospiral",
+			station:   "radiospiral",
+			song: SongInfo{
+				Id:     "9b1c1e5b3f3c4c5e8a0d2f1e6b7a8c9d",
+				Text:   "Steve Roach - Structures from Silence",
+				Artist: "Steve Roach",
+				Title:  "Structures from Silence",
+				Album:  "Structures from Silence",
+				Genre:  "Ambient",
+				Art:    "https://radiospiral.radio/api/station/radiospiral/art/9b1c1e5b3f3c4c5e8a0d2f1e6b7a8c9d-1732200000.jpg",
+			},
+			listeners: ListenersInfo{Total: 14, Unique: 12, Current: 14},
+			duration:  412,
+			elapsed:   95,
+			nextSong:  "Robert Rich - Rainforest",
+		},
+		{
+			fixture:   "live/nowplaying.json",
+			shortcode: "radiospiral",
+			station:   "radiospiral",
+			song: SongInfo{
+				Text: "Pete Kennedy - Live from the Spiral",
+			},
+			live: LiveInfo{
+				IsLive:         true,
+				StreamerName:   "Pete Kennedy",
+				BroadcastStart: 1732294800,
+				Art:            "https://radiospiral.radio/api/station/radiospiral/streamer/31/art-1732000000.jpg",
+			},
+			listeners: ListenersInfo{Total: 14, Unique: 12, Current: 14},
+			duration:  412,
+			elapsed:   95,
+			nextSong:  "Robert Rich - Rainforest",
+		},
+		{
+			fixture:   "strings/nowplaying.json",
+			shortcode: "radiospiral",
+			station:   "radiospiral",
+			song: SongInfo{
+				Text: "Steve Roach - Structures from Silence",
+			},
+			listeners: ListenersInfo{Total: 14, Unique: 12, Current: 14},
+			duration:  412,
+			elapsed:   95,
+			nextSong:  "Robert Rich - Rainforest",
+		},
+		{
+			// One per mount, we pick ours
+			fixture:   "mounts/nowplaying.json",
+			shortcode: "radiospiral",
+			station:   "radiospiral",
+			song: SongInfo{
+				Text: "Steve Roach - Structures from Silence",
+			},
+			listeners: ListenersInfo{Total: 14, Unique: 12, Current: 14},
+			duration:  412,
+			elapsed:   95,
+			nextSong:  "Robert Rich - Rainforest",
+		},
+		{
+			// Nulls everywhere leave the zero values
+			fixture:   "nulls/nowplaying.json",
+			shortcode: "radiospiral",
+			live:      LiveInfo{IsLive: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", test.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := decodeStationResponse(body, test.shortcode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Station.Shortcode != test.station {
+				t.Errorf("station %q, want %q", response.Station.Shortcode, test.station)
+			}
+			song := response.NowPlaying.Song
+			// Only the fields the test cares about
+			if test.song.Id == "" {
+				song = SongInfo{Text: song.Text}
+			}
+			if song != test.song {
+				t.Errorf("song %+v, want %+v", song, test.song)
+			}
+			if response.Live != test.live {
+				t.Errorf("live %+v, want %+v", response.Live, test.live)
+			}
+			if response.Listeners != test.listeners {
+				t.Errorf("listeners %+v, want %+v", response.Listeners, test.listeners)
+			}
+			if response.NowPlaying.Duration != test.duration || response.NowPlaying.Elapsed != test.elapsed {
+				t.Errorf("duration %d and elapsed %d, want %d and %d",
+					response.NowPlaying.Duration, response.NowPlaying.Elapsed, test.duration, test.elapsed)
+			}
+			if response.PlayingNext.Song.Text != test.nextSong {
+				t.Errorf("next song %q, want %q", response.PlayingNext.Song.Text, test.nextSong)
+			}
+		})
+	}
+}
+
+func TestDecodeSchedule(t *testing.T) {
+	upcoming := []BroadcastResponse{
+		{Type: "streamer", Name: "Pete Kennedy", Title: "Pete Kennedy", Description: "Deep space ambient, live", StartTime: 1732294800},
+		{Type: "streamer", Name: "Mystic Tim", Title: "Mystic Tim", Description: "Drones and field recordings", StartTime: 1732381200},
+	}
+	tests := []struct {
+		dir  string
+		want []BroadcastResponse
+	}{
+		{dir: "", want: upcoming},
+		{dir: "live", want: upcoming},
+		{dir: "strings", want: upcoming},
+		{dir: "nulls", want: []BroadcastResponse{{Type: "streamer"}}},
+	}
+
+	for _, test := range tests {
+		t.Run("schedule of "+test.dir, func(t *testing.T) {
+			shows, err := NewFileMetadataSource(filepath.Join("testdata", test.dir)).Schedule()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shows) != len(test.want) {
+				t.Fatalf("%d shows, want %d", len(shows), len(test.want))
+			}
+			for i := range shows {
+				if shows[i] != test.want[i] {
+					t.Errorf("show %d is %+v, want %+v", i, shows[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSelectArtURL(t *testing.T) {
+	const liveArt = "https://radiospiral.radio/api/station/radiospiral/streamer/31/art-1732000000.jpg"
+	const streamerArt = "https://radiospiral.radio/api/station/radiospiral/streamer/31/art"
+	const songArt = "https://radiospiral.radio/api/station/radiospiral/art/0f2e4d6c-1732200000.jpg"
+	const stationArt = "https://radiospiral.radio/static/img/generic_song.jpg"
+
+	tests := []struct {
+		name    string
+		live    LiveInfo
+		songArt string
+		want    string
+	}{
+		{name: "live art", live: LiveInfo{IsLive: true, Art: liveArt}, songArt: stationArt, want: liveArt},
+		{name: "streamer art", live: LiveInfo{IsLive: true}, songArt: streamerArt, want: streamerArt},
+		{name: "song art", songArt: songArt, want: songArt},
+		{name: "station default", songArt: stationArt, want: stationArt},
+		{name: "placeholder", live: LiveInfo{IsLive: true}, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stationData := &StationResponse{Live: test.live}
+			stationData.NowPlaying.Song.Art = test.songArt
+			if art := selectArtURL(stationData); art != test.want {
+				t.Errorf("got %q, want %q", art, test.want)
+			}
+		})
+	}
+
+	if art := selectArtURL(nil); art != "" {
+		t.Errorf("got %q without station data, want none", art)
+	}
+}
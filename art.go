@@ -0,0 +1,275 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Album art cache. Tracks come back around in the rotation and live shows keep
+ * the same art for hours, so there's no point downloading it every time. It also
+ * lets us fetch the art of the next track before it starts playing, so the card
+ * doesn't sit there with the old art while the new one downloads.
+ */
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quality of the art when saved as JPEG
+const ART_JPEG_QUALITY = 95
+
+// How many images we keep around
+const ART_CACHE_SIZE = 16
+
+// How long before the current track ends we fetch the art for the next one
+const ART_PREFETCH_LEAD = 15 * time.Second
+
+// A slow server shouldn't leave the card waiting, we try a few times but give
+// up once the deadline passes and show the placeholder instead
+const ART_FETCH_ATTEMPTS = 3
+const ART_RETRY_DELAY = 500 * time.Millisecond
+
+// After this many failures in a row from a host we leave it alone for a while,
+// so a broken art server doesn't slow down every track change. Once the
+// cooldown is over we try it again, and one more failure starts another.
+const ART_HOST_FAILURES = 3
+const ART_HOST_COOLDOWN = 5 * time.Minute
+
+var ErrLowData = errors.New("not downloading art in low data mode")
+var ErrArtHostDown = errors.New("the art server keeps failing, not trying it for now")
+
+// How an art server has been doing lately
+type artHostHealth struct {
+	failures int
+	// Until when we don't try it
+	cooldown time.Time
+}
+
+type ArtCache struct {
+	mutex  sync.Mutex
+	images map[string]image.Image
+	// URLs in the order they were added, to know which one to drop
+	order []string
+	// How long we try to get an image, retries included
+	deadline time.Duration
+	// Don't download anything, what's already here can still be used
+	lowData bool
+	hosts   map[string]*artHostHealth
+}
+
+func NewArtCache(deadline time.Duration) *ArtCache {
+	return &ArtCache{
+		images:   make(map[string]image.Image),
+		deadline: deadline,
+		hosts:    make(map[string]*artHostHealth),
+	}
+}
+
+// Whether we should try the host now
+func (cache *ArtCache) hostAvailable(host string) bool {
+	health, found := cache.hosts[host]
+	return !found || time.Now().After(health.cooldown)
+}
+
+func (cache *ArtCache) recordFetch(host string, err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	// A missing image is the server working fine, only it being down or
+	// failing counts
+	var statusErr *ArtStatusError
+	if err == nil || errors.As(err, &statusErr) && statusErr.Code < 500 {
+		delete(cache.hosts, host)
+		return
+	}
+	health, found := cache.hosts[host]
+	if !found {
+		health = &artHostHealth{}
+		cache.hosts[host] = health
+	}
+	health.failures += 1
+	if health.failures >= ART_HOST_FAILURES {
+		log.Printf("Art from %s failed %d times in a row, leaving it alone for %s", host, health.failures, ART_HOST_COOLDOWN)
+		health.cooldown = time.Now().Add(ART_HOST_COOLDOWN)
+	}
+}
+
+func (cache *ArtCache) SetDeadline(deadline time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.deadline = deadline
+}
+
+func (cache *ArtCache) SetLowData(lowData bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.lowData = lowData
+}
+
+// Returns the image for the URL, downloading it if we don't have it yet
+func (cache *ArtCache) Get(url string) (image.Image, error) {
+	host := artHost(url)
+	cache.mutex.Lock()
+	img, found := cache.images[url]
+	deadline := cache.deadline
+	lowData := cache.lowData
+	hostAvailable := cache.hostAvailable(host)
+	cache.mutex.Unlock()
+	if found {
+		return img, nil
+	}
+	if lowData {
+		return nil, ErrLowData
+	}
+	if !hostAvailable {
+		return nil, ErrArtHostDown
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		img, err = loadImageURL(ctx, url)
+		if err == nil || attempt >= ART_FETCH_ATTEMPTS || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * ART_RETRY_DELAY):
+		case <-ctx.Done():
+		}
+	}
+	cache.recordFetch(host, err)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if _, found := cache.images[url]; !found {
+		cache.images[url] = img
+		cache.order = append(cache.order, url)
+		if len(cache.order) > ART_CACHE_SIZE {
+			delete(cache.images, cache.order[0])
+			cache.order = cache.order[1:]
+		}
+	}
+
+	return img, nil
+}
+
+// Downloads the image in the background so it is ready when we need it
+func (cache *ArtCache) Prefetch(url string) {
+	go func() {
+		_, err := cache.Get(url)
+		if err != nil && err != ErrLowData && err != ErrArtHostDown {
+			log.Printf("Couldn't prefetch art %s: %s", url, err)
+		}
+	}()
+}
+
+// The host of the art URL, the whole URL if it doesn't parse
+func artHost(artURL string) string {
+	parsed, err := url.Parse(artURL)
+	if err != nil || parsed.Host == "" {
+		return artURL
+	}
+	return parsed.Host
+}
+
+// The image we show when there's no art: the one chosen by the user, or
+// our bundled logo if there's none or it can't be loaded
+func loadPlaceholder(path string) image.Image {
+	if path != "" {
+		img, err := loadImageFile(path)
+		if err == nil {
+			return img
+		}
+		log.Printf("Couldn't load placeholder %s: %s", path, err)
+	}
+
+	return iconImage()
+}
+
+func loadImageFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// The images in the folder, in name order
+func imageFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Couldn't read the images in %s: %s", dir, err)
+		return nil
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".png", ".jpg", ".jpeg":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return files
+}
+
+// Writes the image as JPEG if the extension says so, PNG otherwise
+func encodeImage(writer io.Writer, img image.Image, extension string) error {
+	switch strings.ToLower(extension) {
+	case ".jpg", ".jpeg":
+		return jpeg.Encode(writer, img, &jpeg.Options{Quality: ART_JPEG_QUALITY})
+	}
+	return png.Encode(writer, img)
+}
+
+// A file name out of a track title, without the characters file systems choke on
+func artFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "album art"
+	}
+	return name + ".png"
+}
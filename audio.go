@@ -0,0 +1,88 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * oto reads the audio from ffmpeg on its own goroutine. When ffmpeg goes away
+ * the pipe can be closed right under that read, so we make that a plain end of
+ * stream and let the player know the audio is over. Whoever wants a look at the
+ * audio on its way to oto (to check its level, for instance) gets it here too.
+ */
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How many times we try again after a read that got nothing, and how long we
+// wait before each
+const EMPTY_READ_RETRIES = 10
+const EMPTY_READ_DELAY = 5 * time.Millisecond
+
+type audioReader struct {
+	reader io.ReadCloser
+	// Called once when the audio ends without us closing it
+	onEnd   func()
+	endOnce sync.Once
+	closed  atomic.Bool
+	// Called with the audio read, in oto's goroutine, so it must be quick
+	onData func(data []byte)
+}
+
+func newAudioReader(reader io.ReadCloser, onEnd func(), onData func(data []byte)) *audioReader {
+	return &audioReader{reader: reader, onEnd: onEnd, onData: onData}
+}
+
+func (audio *audioReader) Read(p []byte) (int, error) {
+	n, err := audio.reader.Read(p)
+	// An empty read without an error is not the end of the audio, just
+	// nothing to read yet. Wait a bit and try again, so the sink doesn't spin
+	// on them, and if there's still nothing pass it on as it is.
+	for retry := 0; n == 0 && err == nil && len(p) > 0 && retry < EMPTY_READ_RETRIES; retry++ {
+		time.Sleep(EMPTY_READ_DELAY)
+		n, err = audio.reader.Read(p)
+	}
+	if n > 0 && audio.onData != nil {
+		audio.onData(p[:n])
+	}
+	if err == nil {
+		return n, nil
+	}
+
+	if errors.Is(err, os.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		err = io.EOF
+	} else if err != io.EOF {
+		log.Printf("Error reading the audio: %s", err)
+	}
+
+	if !audio.closed.Load() && audio.onEnd != nil {
+		audio.endOnce.Do(audio.onEnd)
+	}
+	return n, err
+}
+
+// Closes the pipe, the reads failing after this are expected
+func (audio *audioReader) Close() error {
+	audio.closed.Store(true)
+	return audio.reader.Close()
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Trouble opening the audio device. The usual one is another application holding
+ * it for itself (WASAPI exclusive mode on Windows, a raw ALSA device on Linux).
+ * oto already opens the device in shared mode and falls back to WinMM on Windows,
+ * so all we can do is tell the user what's going on.
+ */
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// How the audio backends say the device is taken
+var AUDIO_DEVICE_IN_USE_SIGNATURES = []string{
+	// AUDCLNT_E_DEVICE_IN_USE (0x8889000A), as oto prints it
+	"AUDCLNT_ERR(2290679818)",
+	"MMSYSERR_ALLOCATED",
+	"Device or resource busy",
+}
+
+type AudioDeviceError struct {
+	Err error
+}
+
+func (deviceErr *AudioDeviceError) Error() string {
+	return "Couldn't open the audio device: " + deviceErr.Err.Error()
+}
+
+func (deviceErr *AudioDeviceError) Unwrap() error {
+	return deviceErr.Err
+}
+
+// Another application has the device for itself
+func (deviceErr *AudioDeviceError) InUse() bool {
+	for _, signature := range AUDIO_DEVICE_IN_USE_SIGNATURES {
+		if strings.Contains(deviceErr.Err.Error(), signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// What the user can do about it
+func (deviceErr *AudioDeviceError) Advice() string {
+	if errors.Is(deviceErr.Err, ErrAudioDeviceNeedsRestart) {
+		return "The audio library only gets one try at the audio device each run. " +
+			"Check your sound card or headphones are connected and working, then restart the player."
+	}
+	if !deviceErr.InUse() {
+		return "Check your sound card or headphones are connected and working, then restart the player."
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return "Another application is using the audio device exclusively. Close it, or untick " +
+			"\"Allow applications to take exclusive control of this device\" in the advanced " +
+			"properties of the device in the Windows sound settings, then restart the player."
+	case "linux":
+		return "Another application is holding the sound card. Close it, or use PulseAudio or " +
+			"PipeWire so they can share it, then restart the player."
+	}
+	return "Another application is using the audio device. Close it and restart the player."
+}
+
+// Starts a new instance of the player with the same arguments, the audio device
+// can only be opened once per process so that's the only way to try again
+func restartPlayer() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Start()
+}
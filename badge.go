@@ -0,0 +1,78 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * A small badge in the header telling at a glance whether someone is on the
+ * air (LIVE) or the station is playing on its own (AUTO).
+ */
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+)
+
+var BADGE_LIVE_COLOR = color.NRGBA{R: 0xd3, G: 0x2f, B: 0x2f, A: 0xff}
+var BADGE_AUTO_COLOR = color.NRGBA{R: 0x75, G: 0x75, B: 0x75, A: 0xff}
+
+type LiveBadge struct {
+	*fyne.Container
+	background *canvas.Rectangle
+	text       *canvas.Text
+}
+
+// Hidden until we know what's on
+func NewLiveBadge() *LiveBadge {
+	background := canvas.NewRectangle(BADGE_AUTO_COLOR)
+	background.CornerRadius = theme.InputRadiusSize()
+	text := canvas.NewText("", color.White)
+	text.TextStyle = fyne.TextStyle{Bold: true}
+	text.TextSize = theme.CaptionTextSize()
+	text.Alignment = fyne.TextAlignCenter
+
+	badge := &LiveBadge{
+		Container:  container.NewStack(background, container.NewPadded(text)),
+		background: background,
+		text:       text,
+	}
+	badge.Hide()
+	return badge
+}
+
+func (badge *LiveBadge) SetLive(live bool) {
+	queueUIUpdate(func() {
+		if live {
+			badge.text.Text = "LIVE"
+			badge.background.FillColor = BADGE_LIVE_COLOR
+		} else {
+			badge.text.Text = "AUTO"
+			badge.background.FillColor = BADGE_AUTO_COLOR
+		}
+		badge.Show()
+		badge.Refresh()
+	})
+}
+
+// Not playing, or we don't know: no badge
+func (badge *LiveBadge) Clear() {
+	queueUIUpdate(badge.Hide)
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Times of the day as the user likes them: 24 hours, 12 hours, or whatever is
+ * usual where they are. Everything showing a time goes through formatClock, so
+ * they all agree.
+ */
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ClockAuto = "auto"
+	Clock24h  = "24h"
+	Clock12h  = "12h"
+)
+
+var CLOCK_OPTIONS = []string{ClockAuto, Clock24h, Clock12h}
+
+// Where the 12 hour clock is the usual one, as in the territory of the locale
+var TWELVE_HOUR_TERRITORIES = []string{"US", "CA", "AU", "NZ", "PH", "IN", "PK", "BD", "EG", "SA", "MY"}
+
+var twelveHourClock atomic.Bool
+
+func setClockStyle(style string) {
+	switch style {
+	case Clock12h:
+		twelveHourClock.Store(true)
+	case Clock24h:
+		twelveHourClock.Store(false)
+	default:
+		twelveHourClock.Store(localeUsesTwelveHours())
+	}
+}
+
+// Go doesn't know about locales, so we look at the environment like the C
+// library would, as in LANG=en_US.UTF-8
+func localeUsesTwelveHours() bool {
+	for _, variable := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		locale := os.Getenv(variable)
+		if locale == "" {
+			continue
+		}
+		locale, _, _ = strings.Cut(locale, ".")
+		_, territory, _ := strings.Cut(locale, "_")
+		return slices.Contains(TWELVE_HOUR_TERRITORIES, territory)
+	}
+	return false
+}
+
+// Like 15:04 or 3:04 PM
+func formatClock(moment time.Time) string {
+	if twelveHourClock.Load() {
+		return moment.Format("3:04 PM")
+	}
+	return moment.Format("15:04")
+}
+
+// Like 15:04:05 or 3:04:05 PM
+func formatClockSeconds(moment time.Time) string {
+	if twelveHourClock.Load() {
+		return moment.Format("3:04:05 PM")
+	}
+	return moment.Format("15:04:05")
+}
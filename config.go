@@ -0,0 +1,90 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Settings from a config file (-config), for setups where nobody is around to
+ * use the settings dialog. It's JSON, so we don't need anything else to read it.
+ * Everything in it is optional: what's there takes precedence over the saved
+ * settings, and the command line flags take precedence over the file.
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// We don't want to hammer the API, whatever the config says
+const MIN_POLL_INTERVAL = time.Minute
+
+type Config struct {
+	StreamURL    string   `json:"stream_url"`
+	FFmpeg       string   `json:"ffmpeg"`
+	Volume       *float64 `json:"volume"`
+	Autoplay     *bool    `json:"autoplay"`
+	Log          *bool    `json:"log"`
+	PollInterval string   `json:"poll_interval"`
+	// Not part of AzuraCast, we only look for announcements if there's one
+	AnnouncementURL string `json:"announcement_url"`
+
+	pollInterval time.Duration
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Complain about typos instead of silently ignoring them
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var config Config
+	err = decoder.Decode(&config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if config.Volume != nil && (*config.Volume < 0 || *config.Volume > 1) {
+		return nil, fmt.Errorf("%s: volume must be between 0 and 1", path)
+	}
+
+	if config.PollInterval != "" {
+		config.pollInterval, err = time.ParseDuration(config.PollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("%s: poll_interval: %w", path, err)
+		}
+		if config.pollInterval < MIN_POLL_INTERVAL {
+			return nil, fmt.Errorf("%s: poll_interval must be at least %s", path, MIN_POLL_INTERVAL)
+		}
+	}
+
+	return &config, nil
+}
+
+// The poll interval from the file, or the default one if it doesn't set it
+func (config *Config) PollIntervalOr(fallback time.Duration) time.Duration {
+	if config.pollInterval == 0 {
+		return fallback
+	}
+	return config.pollInterval
+}
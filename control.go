@@ -0,0 +1,348 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Local HTTP API to control a running player, so it can be driven from scripts
+ * and hotkeys. It only listens on localhost. The same binary doubles as the
+ * client: "radiospiral play", "radiospiral volume 0.5"... talk to the API of
+ * the instance that is already running. There's also a small web page with the
+ * same controls, for a phone on the couch.
+ */
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const CONTROL_ADDRESS = "127.0.0.1:8957"
+
+// Where we listen when asked to be reachable from the local network
+const CONTROL_LAN_ADDRESS = "0.0.0.0:8957"
+
+//go:embed web
+var webFiles embed.FS
+
+// How long the client waits for the running instance to answer
+const CONTROL_CLIENT_TIMEOUT = 5 * time.Second
+
+// Every now and then we send something down the event streams, so we notice
+// the clients that went away
+const CONTROL_EVENTS_KEEPALIVE = 30 * time.Second
+
+// Statuses a slow event client can fall behind before we skip some for it
+const CONTROL_EVENTS_BACKLOG = 8
+
+// What the API tells about the player
+type ControlStatus struct {
+	Status  string  `json:"status"`
+	Station string  `json:"station"`
+	Title   string  `json:"title"`
+	Volume  float64 `json:"volume"`
+	Format  string  `json:"format,omitempty"`
+}
+
+// What the API calls in the app to get things done
+type ControlHandlers struct {
+	Play      func()
+	Stop      func()
+	SetVolume func(volume float64) error
+	Status    func() ControlStatus
+}
+
+// Lets the clients of /events know when the status changes
+type ControlEvents struct {
+	mutex       sync.Mutex
+	last        ControlStatus
+	subscribers map[chan ControlStatus]bool
+}
+
+func NewControlEvents() *ControlEvents {
+	return &ControlEvents{subscribers: make(map[chan ControlStatus]bool)}
+}
+
+// Sends the status to everyone listening, unless nothing changed. Clients
+// too slow to keep up miss some statuses, but never the latest one for long.
+func (events *ControlEvents) Publish(status ControlStatus) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	if status == events.last {
+		return
+	}
+	events.last = status
+	for subscriber := range events.subscribers {
+		select {
+		case subscriber <- status:
+		default:
+		}
+	}
+}
+
+func (events *ControlEvents) subscribe() chan ControlStatus {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	subscriber := make(chan ControlStatus, CONTROL_EVENTS_BACKLOG)
+	events.subscribers[subscriber] = true
+	return subscriber
+}
+
+func (events *ControlEvents) unsubscribe(subscriber chan ControlStatus) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	delete(events.subscribers, subscriber)
+}
+
+// Server-Sent Events with the status, the current one first and then each
+// time it changes, until the client goes away
+func serveControlEvents(w http.ResponseWriter, r *http.Request, events *ControlEvents, status ControlStatus) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	subscriber := events.subscribe()
+	defer events.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(status ControlStatus) error {
+		data, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+		flusher.Flush()
+		return err
+	}
+
+	if send(status) != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(CONTROL_EVENTS_KEEPALIVE)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case status := <-subscriber:
+			if send(status) != nil {
+				return
+			}
+		case <-keepalive.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Starts serving the API in the background. Fails if the address is taken,
+// usually because there's another instance running.
+func startControlServer(address string, handlers ControlHandlers, events *ControlEvents) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/play", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		handlers.Play()
+		writeControlStatus(w, handlers.Status())
+	})
+	mux.HandleFunc("/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		handlers.Stop()
+		writeControlStatus(w, handlers.Status())
+	})
+	mux.HandleFunc("/volume", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		volume, err := parseVolume(r.FormValue("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = handlers.SetVolume(volume)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeControlStatus(w, handlers.Status())
+	})
+	mux.HandleFunc("/nowplaying", func(w http.ResponseWriter, r *http.Request) {
+		writeControlStatus(w, handlers.Status())
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		serveControlEvents(w, r, events, handlers.Status())
+	})
+
+	webRoot, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	mux.Handle("/", http.FileServer(http.FS(webRoot)))
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	lan := !listener.Addr().(*net.TCPAddr).IP.IsLoopback()
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedControlRequest(r, port, lan) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})}
+	go server.Serve(listener)
+	return server, nil
+}
+
+// Web pages the user happens to have open can reach us too. They are kept out
+// by the Host, which for a rebound DNS name is the attacker's name and not
+// ours, and by the Origin the browser sends along when it's another site.
+func allowedControlRequest(r *http.Request, port string, lan bool) bool {
+	host, hostPort, err := net.SplitHostPort(r.Host)
+	if err != nil || hostPort != port {
+		return false
+	}
+	if host != "localhost" {
+		// From the local network they reach us by address, names could
+		// point anywhere
+		ip := net.ParseIP(host)
+		if ip == nil || (!lan && !ip.IsLoopback()) {
+			return false
+		}
+	}
+	origin := r.Header.Get("Origin")
+	return origin == "" || origin == "http://"+r.Host
+}
+
+func writeControlStatus(w http.ResponseWriter, status ControlStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func parseVolume(text string) (float64, error) {
+	volume, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || volume < 0 || volume > 1 {
+		return 0, errors.New("the volume must be a number between 0 and 1")
+	}
+	return volume, nil
+}
+
+// The commands the client understands
+var CONTROL_COMMANDS = []string{"play", "stop", "volume", "nowplaying"}
+
+// Runs a client command against the running instance, printing the outcome.
+// Returns the exit code for the process.
+func runControlCommand(address string, args []string) int {
+	client := &http.Client{Timeout: CONTROL_CLIENT_TIMEOUT}
+	base := "http://" + address
+
+	var resp *http.Response
+	var err error
+	switch args[0] {
+	case "play", "stop":
+		resp, err = client.Post(base+"/"+args[0], "", nil)
+	case "volume":
+		if len(args) != 2 {
+			fmt.Println("Usage: radiospiral volume <0.0 to 1.0>")
+			return 2
+		}
+		_, err = parseVolume(args[1])
+		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			return 2
+		}
+		resp, err = client.PostForm(base+"/volume", url.Values{"level": {args[1]}})
+	case "nowplaying":
+		resp, err = client.Get(base + "/nowplaying")
+	default:
+		fmt.Printf("Unknown command %q, use one of: %s\n", args[0], strings.Join(CONTROL_COMMANDS, ", "))
+		return 2
+	}
+
+	if err != nil {
+		// Nobody listening means there's no instance running
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			fmt.Println("RadioSpiral Player is not running")
+		} else {
+			fmt.Printf("ERROR: Couldn't talk to RadioSpiral Player: %s\n", err)
+		}
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		fmt.Printf("ERROR: %s\n", strings.TrimSpace(string(message)))
+		return 1
+	}
+
+	var status ControlStatus
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	if err != nil {
+		fmt.Printf("ERROR: Unexpected answer from RadioSpiral Player: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("Status: %s\n", status.Status)
+	fmt.Printf("Station: %s\n", status.Station)
+	if status.Title != "" {
+		fmt.Printf("Title: %s\n", status.Title)
+	}
+	fmt.Printf("Volume: %.0f%%\n", status.Volume*100)
+	if status.Format != "" {
+		fmt.Printf("Quality: %s\n", status.Format)
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedControlRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		origin  string
+		lan     bool
+		allowed bool
+	}{
+		{name: "client", host: "127.0.0.1:8957", allowed: true},
+		{name: "localhost", host: "localhost:8957", allowed: true},
+		{name: "own page", host: "127.0.0.1:8957", origin: "http://127.0.0.1:8957", allowed: true},
+		{name: "other site", host: "127.0.0.1:8957", origin: "http://example.com", allowed: false},
+		{name: "rebound name", host: "attacker.example.com:8957", allowed: false},
+		{name: "other port", host: "127.0.0.1:8080", allowed: false},
+		{name: "no port", host: "127.0.0.1", allowed: false},
+		{name: "network address", host: "192.168.1.20:8957", allowed: false},
+		{name: "network address on the LAN", host: "192.168.1.20:8957", lan: true, allowed: true},
+		{name: "rebound name on the LAN", host: "attacker.example.com:8957", lan: true, allowed: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/play", nil)
+			r.Host = test.host
+			if test.origin != "" {
+				r.Header.Set("Origin", test.origin)
+			}
+			if allowed := allowedControlRequest(r, "8957", test.lan); allowed != test.allowed {
+				t.Errorf("allowed %t, want %t", allowed, test.allowed)
+			}
+		})
+	}
+}
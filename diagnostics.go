@@ -0,0 +1,154 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Diagnostics for bug reports: what we are running on, what we are playing (and
+ * which server is really serving it, streams often redirect to a CDN) and what
+ * ffmpeg has been telling us lately.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long we wait for the stream server when following its redirects
+const STREAM_RESOLVE_TIMEOUT = 5 * time.Second
+
+// ffmpeg gives up after as many
+const MAX_STREAM_REDIRECTS = 10
+
+// Keeps the last lines added to it
+type RingBuffer struct {
+	mutex sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func NewRingBuffer(size int) *RingBuffer {
+	return &RingBuffer{lines: make([]string, size)}
+}
+
+func (ring *RingBuffer) Add(line string) {
+	ring.mutex.Lock()
+	defer ring.mutex.Unlock()
+
+	ring.lines[ring.next] = line
+	ring.next = (ring.next + 1) % len(ring.lines)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// The lines we have, oldest first
+func (ring *RingBuffer) Lines() []string {
+	ring.mutex.Lock()
+	defer ring.mutex.Unlock()
+
+	if !ring.full {
+		return append([]string{}, ring.lines[:ring.next]...)
+	}
+	return append(append([]string{}, ring.lines[ring.next:]...), ring.lines[:ring.next]...)
+}
+
+// First line of ffmpeg -version, or why we couldn't get it
+func ffmpegVersion(playerCmd string) string {
+	output, err := exec.Command(playerCmd, "-version").Output()
+	if err != nil {
+		return fmt.Sprintf("unavailable (%s)", err)
+	}
+
+	version, _, _ := strings.Cut(string(output), "\n")
+	return version
+}
+
+// Follows the redirects of the stream, like ffmpeg does, and returns where we
+// end up and the URLs on the way there. We only wait for the headers, the
+// stream itself never ends. It's one more listener for the station (and some
+// streams only take one), so only for the diagnostics the user asked for.
+func resolveStreamURL(streamURL string) (string, []string, error) {
+	input_url, headers := splitStreamCredentials(streamURL)
+	req, err := http.NewRequest(http.MethodGet, input_url, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if name, value, found := strings.Cut(strings.TrimSpace(headers), ": "); found {
+		req.Header.Set(name, value)
+	}
+
+	var redirects []string
+	client := &http.Client{
+		Timeout: STREAM_RESOLVE_TIMEOUT,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= MAX_STREAM_REDIRECTS {
+				return errors.New("too many redirects")
+			}
+			redirects = append(redirects, req.URL.String())
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", redirects, err
+	}
+	resp.Body.Close()
+
+	final := resp.Request.URL.String()
+	if resp.StatusCode != http.StatusOK {
+		return final, redirects, errors.New(resp.Status)
+	}
+	return final, redirects, nil
+}
+
+func buildDiagnostics(playerCmd string, status string, streamURL string, currentSong string, output *RingBuffer) string {
+	var report strings.Builder
+
+	fmt.Fprintln(&report, "RadioSpiral Player diagnostics")
+	fmt.Fprintf(&report, "OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&report, "Go: %s\n", runtime.Version())
+	fmt.Fprintf(&report, "ffmpeg: %s\n", ffmpegVersion(playerCmd))
+	fmt.Fprintf(&report, "Status: %s\n", status)
+	fmt.Fprintf(&report, "Stream: %s\n", redactURL(streamURL))
+	if streamURL != "" {
+		final, redirects, err := resolveStreamURL(streamURL)
+		for _, redirect := range redirects {
+			fmt.Fprintf(&report, "Redirected to: %s\n", redactURL(redirect))
+		}
+		if err != nil {
+			fmt.Fprintf(&report, "Stream check: %s\n", redactURLs(err.Error()))
+		} else {
+			fmt.Fprintf(&report, "Stream served by: %s\n", redactURL(final))
+		}
+	}
+	fmt.Fprintf(&report, "Current song: %s\n", currentSong)
+	fmt.Fprintln(&report)
+	fmt.Fprintln(&report, "Recent ffmpeg output:")
+	for _, line := range output.Lines() {
+		fmt.Fprintln(&report, line)
+	}
+
+	return report.String()
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * How the track is shown. Users can write their own template, like
+ * "{artist} — {title} [{album}]", and we fill it with what we know of the
+ * song. Fields without a value are left out along with what goes with them:
+ * brackets stick to their field, and the text in between fields only shows
+ * up between fields that have something.
+ */
+
+import (
+	"strings"
+	"unicode"
+)
+
+// What a stream title "Artist - Title" looks like with the default template
+const DEFAULT_DISPLAY_TEMPLATE = "{artist} - {title}"
+
+// Brackets and quotes that go with the field after or before them
+const TEMPLATE_OPENERS = "([{<«\"'"
+const TEMPLATE_CLOSERS = ")]}>»\"'"
+
+func songField(song SongInfo, name string) (string, bool) {
+	switch name {
+	case "artist":
+		return song.Artist, true
+	case "title":
+		return song.Title, true
+	case "album":
+		return song.Album, true
+	case "genre":
+		return song.Genre, true
+	case "text":
+		return song.Text, true
+	}
+	return "", false
+}
+
+// A field of the template, with the text around it that belongs to it
+type templateField struct {
+	value string
+	// Text between this field and the previous one
+	separator string
+	// Brackets right before and after the field
+	opener string
+	closer string
+}
+
+// Splits the text between two fields: closing brackets right after the
+// previous one are its closer, opening brackets right before the next one are
+// its opener, and anything left is the separator
+func splitTemplateText(text string) (closer string, separator string, opener string) {
+	closerEnd := strings.IndexFunc(text, func(r rune) bool { return !strings.ContainsRune(TEMPLATE_CLOSERS, r) })
+	if closerEnd < 0 {
+		return text, "", ""
+	}
+	openerStart := strings.LastIndexFunc(text, func(r rune) bool { return !strings.ContainsRune(TEMPLATE_OPENERS, r) }) + 1
+	if openerStart < closerEnd {
+		openerStart = closerEnd
+	}
+	return text[:closerEnd], text[closerEnd:openerStart], text[openerStart:]
+}
+
+func renderDisplayTemplate(template string, song SongInfo) string {
+	var fields []templateField
+	text := ""
+	rest := template
+	for rest != "" {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			text += rest
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			text += rest
+			break
+		}
+		end += start
+
+		value, known := songField(song, rest[start+1:end])
+		if !known {
+			// Not a field, just text with braces in it
+			text += rest[:end+1]
+			rest = rest[end+1:]
+			continue
+		}
+
+		text += rest[:start]
+		closer, separator, opener := splitTemplateText(text)
+		if len(fields) > 0 {
+			fields[len(fields)-1].closer = closer
+		} else {
+			// Before the first field there's nothing to close or separate
+			opener = text
+			separator = ""
+		}
+		fields = append(fields, templateField{
+			value:     strings.TrimSpace(value),
+			separator: separator,
+			opener:    opener,
+		})
+		text = ""
+		rest = rest[end+1:]
+	}
+	if len(fields) == 0 {
+		return strings.TrimSpace(text)
+	}
+	fields[len(fields)-1].closer = text
+
+	var rendered strings.Builder
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		if rendered.Len() > 0 {
+			rendered.WriteString(field.separator)
+		}
+		rendered.WriteString(field.opener)
+		rendered.WriteString(field.value)
+		rendered.WriteString(field.closer)
+	}
+	return strings.TrimFunc(rendered.String(), unicode.IsSpace)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import "testing"
+
+func TestRenderDisplayTemplate(t *testing.T) {
+	full := SongInfo{Artist: "Steve Roach", Title: "Structures from Silence", Album: "Structures from Silence", Genre: "Ambient"}
+
+	tests := []struct {
+		name     string
+		template string
+		song     SongInfo
+		want     string
+	}{
+		{"default", DEFAULT_DISPLAY_TEMPLATE, full, "Steve Roach - Structures from Silence"},
+		{"default without artist", DEFAULT_DISPLAY_TEMPLATE, SongInfo{Title: "Rainforest"}, "Rainforest"},
+		{"default without title", DEFAULT_DISPLAY_TEMPLATE, SongInfo{Artist: "Robert Rich"}, "Robert Rich"},
+		{"nothing known", DEFAULT_DISPLAY_TEMPLATE, SongInfo{}, ""},
+		{"album in brackets", "{artist} — {title} [{album}]", full, "Steve Roach — Structures from Silence [Structures from Silence]"},
+		{"brackets go with a missing album", "{artist} — {title} [{album}]", SongInfo{Artist: "Robert Rich", Title: "Rainforest"}, "Robert Rich — Rainforest"},
+		{"separator goes with a missing artist", "{artist} — {title} [{album}]", SongInfo{Title: "Rainforest", Album: "Rainforest"}, "Rainforest [Rainforest]"},
+		{"missing first field", "({genre}) {artist} - {title}", SongInfo{Artist: "Robert Rich", Title: "Rainforest"}, "Robert Rich - Rainforest"},
+		{"first field in brackets", "({genre}) {artist} - {title}", full, "(Ambient) Steve Roach - Structures from Silence"},
+		{"whitespace in values", "{artist} - {title}", SongInfo{Artist: "  Robert Rich ", Title: " Rainforest\n"}, "Robert Rich - Rainforest"},
+		{"unknown fields are text", "{foo} {title}", SongInfo{Title: "Rainforest"}, "{foo} Rainforest"},
+		{"no fields at all", "  RadioSpiral  ", full, "RadioSpiral"},
+		{"unclosed brace", "{artist} - {title", full, "Steve Roach - {title"},
+		{"whole stream title", "{text}", SongInfo{Text: "Steve Roach - Structures from Silence"}, "Steve Roach - Structures from Silence"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := renderDisplayTemplate(test.template, test.song)
+			if got != test.want {
+				t.Errorf("renderDisplayTemplate(%q) = %q, want %q", test.template, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Playing through mpv instead of ffmpeg and oto, for those who trust its
+ * buffering more. StreamPlayer still starts and reaps the process and we read
+ * its output for the titles, but the audio never goes through us: pause and
+ * volume are sent to mpv through its IPC socket. Windows uses named pipes for
+ * that, which we don't talk, so there mpv plays at the volume it started with.
+ */
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// How long we wait for mpv to answer over IPC
+const MPV_IPC_TIMEOUT = time.Second
+
+// Where mpv listens for commands, empty where we can't use it
+func mpvSocketPath() string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("radiospiral-mpv-%d.sock", os.Getpid()))
+}
+
+// mpv goes from 0 to 100. What we give it is the volume of the output, on the
+// command line and over IPC alike. That's the slider as it is (with the ducks
+// and the fade), mpv puts it on its own curve.
+func mpvVolume(volume float64) float64 {
+	return volume * 100
+}
+
+// Arguments to play the stream with mpv, volume of the output from 0 to 1
+func mpvArgs(input_url string, headers string, socket string, volume float64) []string {
+	args := []string{
+		"--no-video",
+		"--no-input-terminal",
+		"--term-status-msg=",
+		fmt.Sprintf("--volume=%.0f", mpvVolume(volume)),
+	}
+	if headers != "" {
+		args = append(args, "--http-header-fields="+headers)
+	}
+	if socket != "" {
+		args = append(args, "--input-ipc-server="+socket)
+	}
+	return append(args, input_url)
+}
+
+// Sends a command to mpv and waits for its answer
+func mpvCommand(socket string, command ...interface{}) error {
+	if socket == "" {
+		return fmt.Errorf("no IPC with mpv on %s", runtime.GOOS)
+	}
+
+	conn, err := net.DialTimeout("unix", socket, MPV_IPC_TIMEOUT)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(MPV_IPC_TIMEOUT))
+
+	request, err := json.Marshal(map[string]interface{}{"command": command})
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(request, '\n'))
+	if err != nil {
+		return err
+	}
+
+	// mpv may send events before the answer, skip them
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var reply struct {
+			Event string `json:"event"`
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &reply) != nil || reply.Event != "" {
+			continue
+		}
+		if reply.Error != "success" {
+			return fmt.Errorf("mpv: %s", reply.Error)
+		}
+		return nil
+	}
+	if scanner.Err() != nil {
+		return scanner.Err()
+	}
+	return fmt.Errorf("mpv closed the connection")
+}
+
+// mpv playing the stream, seen as a SinkPlayer so StreamPlayer can treat it
+// like the audio it plays itself
+type mpvPlayer struct {
+	mutex      sync.Mutex
+	socket     string
+	volume     float64
+	bufferSize int
+	playing    bool
+	// mpv starts playing on its own, the first Play is just us catching up
+	started bool
+}
+
+func newMPVPlayer(socket string, volume float64) *mpvPlayer {
+	return &mpvPlayer{socket: socket, volume: volume, bufferSize: PLAYER_BUFFER_SIZE}
+}
+
+func (player *mpvPlayer) Play() {
+	player.mutex.Lock()
+	started := player.started
+	player.started = true
+	player.playing = true
+	player.mutex.Unlock()
+
+	if !started {
+		return
+	}
+	if err := mpvCommand(player.socket, "set_property", "pause", false); err != nil {
+		log.Printf("Couldn't tell mpv to play: %s", err)
+	}
+}
+
+func (player *mpvPlayer) Pause() {
+	player.mutex.Lock()
+	player.playing = false
+	player.mutex.Unlock()
+
+	if err := mpvCommand(player.socket, "set_property", "pause", true); err != nil {
+		log.Printf("Couldn't pause mpv: %s", err)
+	}
+}
+
+func (player *mpvPlayer) IsPlaying() bool {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.playing
+}
+
+func (player *mpvPlayer) Volume() float64 {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.volume
+}
+
+func (player *mpvPlayer) SetVolume(volume float64) {
+	player.mutex.Lock()
+	player.volume = volume
+	player.mutex.Unlock()
+
+	if err := mpvCommand(player.socket, "set_property", "volume", mpvVolume(volume)); err != nil {
+		log.Printf("Couldn't set the volume of mpv: %s", err)
+	}
+}
+
+// mpv keeps its own buffer, as far as we know it's always full
+func (player *mpvPlayer) BufferedSize() int {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.bufferSize
+}
+
+func (player *mpvPlayer) SetBufferSize(bufferSize int) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.bufferSize = bufferSize
+}
+
+// StreamPlayer gets rid of the process, we just clean the socket
+func (player *mpvPlayer) Close() error {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	player.playing = false
+	if player.socket != "" {
+		os.Remove(player.socket)
+	}
+	return nil
+}
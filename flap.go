@@ -0,0 +1,107 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Some broken encoders flip between two titles every second or so. Each change
+ * means a notification, a history entry and a station query, so when the title
+ * changes too often we stop passing it along until it settles for a while.
+ */
+
+import (
+	"sync"
+	"time"
+)
+
+// More changes than these within FLAP_WINDOW and the title is flapping
+const FLAP_MAX_CHANGES = 6
+const FLAP_WINDOW = 30 * time.Second
+
+// How long the title has to stay the same for us to trust it again
+const FLAP_SETTLE = 30 * time.Second
+
+type TitleFlapGuard struct {
+	mutex   sync.Mutex
+	changes []time.Time
+	frozen  bool
+	latest  string
+	settle  *time.Timer
+	// Called when the title starts flapping and we stop passing it along
+	OnUnstable func()
+	// Called with the title once it stayed the same for FLAP_SETTLE
+	OnSettled func(title string)
+}
+
+// Tells if the new title should go through, or if it's flapping. While it
+// flaps each change holds off the end of it a bit more.
+func (guard *TitleFlapGuard) Change(title string, at time.Time) bool {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	guard.latest = title
+	if guard.frozen {
+		guard.settle.Reset(FLAP_SETTLE)
+		return false
+	}
+
+	recent := guard.changes[:0]
+	for _, change := range guard.changes {
+		if at.Sub(change) < FLAP_WINDOW {
+			recent = append(recent, change)
+		}
+	}
+	guard.changes = append(recent, at)
+	if len(guard.changes) <= FLAP_MAX_CHANGES {
+		return true
+	}
+
+	guard.frozen = true
+	guard.changes = nil
+	guard.settle = time.AfterFunc(FLAP_SETTLE, guard.settled)
+	if guard.OnUnstable != nil {
+		go guard.OnUnstable()
+	}
+	return false
+}
+
+func (guard *TitleFlapGuard) settled() {
+	guard.mutex.Lock()
+	if !guard.frozen {
+		guard.mutex.Unlock()
+		return
+	}
+	guard.frozen = false
+	title := guard.latest
+	guard.mutex.Unlock()
+
+	if guard.OnSettled != nil {
+		guard.OnSettled(title)
+	}
+}
+
+// Forgets about the stream we were on, a new one starts with a clean slate
+func (guard *TitleFlapGuard) Reset() {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	if guard.frozen {
+		guard.settle.Stop()
+	}
+	guard.frozen = false
+	guard.changes = nil
+}
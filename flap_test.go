@@ -0,0 +1,92 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTitleFlapGuardFreezesFlapping(t *testing.T) {
+	unstable := make(chan struct{}, 1)
+	var settledTitle string
+	guard := &TitleFlapGuard{
+		OnUnstable: func() { unstable <- struct{}{} },
+		OnSettled:  func(title string) { settledTitle = title },
+	}
+	defer guard.Reset()
+
+	// A broken encoder going back and forth every second
+	start := time.Now()
+	titles := []string{"Artist - Title", "ARTIST - TITLE"}
+	for i := 0; i < FLAP_MAX_CHANGES; i++ {
+		if !guard.Change(titles[i%2], start.Add(time.Duration(i)*time.Second)) {
+			t.Fatalf("change %d held back, it's not flapping yet", i+1)
+		}
+	}
+	for i := FLAP_MAX_CHANGES; i < 3*FLAP_MAX_CHANGES; i++ {
+		if guard.Change(titles[i%2], start.Add(time.Duration(i)*time.Second)) {
+			t.Fatalf("change %d went through while flapping", i+1)
+		}
+	}
+	select {
+	case <-unstable:
+	case <-time.After(time.Second):
+		t.Fatal("not told the title is unstable")
+	}
+
+	// As if it stayed the same for FLAP_SETTLE
+	guard.settled()
+	if settledTitle != titles[(3*FLAP_MAX_CHANGES-1)%2] {
+		t.Errorf("settled on %q, want the last title", settledTitle)
+	}
+	if !guard.Change("Next - Track", start.Add(time.Minute)) {
+		t.Error("a change after settling was held back")
+	}
+}
+
+func TestTitleFlapGuardLetsRegularChangesThrough(t *testing.T) {
+	guard := &TitleFlapGuard{}
+	defer guard.Reset()
+
+	// Short tracks, but tracks
+	start := time.Now()
+	interval := FLAP_WINDOW / FLAP_MAX_CHANGES
+	for i := 0; i < 5*FLAP_MAX_CHANGES; i++ {
+		if !guard.Change("Track", start.Add(time.Duration(i)*interval)) {
+			t.Fatalf("change %d held back, one every %s isn't flapping", i+1, interval)
+		}
+	}
+}
+
+func TestTitleFlapGuardReset(t *testing.T) {
+	guard := &TitleFlapGuard{}
+	start := time.Now()
+	for i := 0; i <= FLAP_MAX_CHANGES; i++ {
+		guard.Change("Title", start)
+	}
+	if guard.Change("Title", start) {
+		t.Fatal("not flapping after all those changes")
+	}
+
+	// A new stream starts over
+	guard.Reset()
+	if !guard.Change("Title", start) {
+		t.Error("still held back after a reset")
+	}
+}
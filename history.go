@@ -0,0 +1,136 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * The tracks played in this session, newest first. The history dialog shows
+ * how long ago each one played, and keeps those times up to date while open.
+ */
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How many tracks we remember
+const HISTORY_SIZE = 50
+
+// How often the times in the open history dialog are refreshed
+const HISTORY_REFRESH_INTERVAL = 30 * time.Second
+
+type HistoryEntry struct {
+	Title    string
+	PlayedAt time.Time
+}
+
+type SongHistory struct {
+	mutex   sync.Mutex
+	entries []HistoryEntry
+}
+
+func (history *SongHistory) Add(title string, playedAt time.Time) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	// Some stations send the same title again now and then
+	if len(history.entries) > 0 && history.entries[0].Title == title {
+		return
+	}
+	history.entries = append([]HistoryEntry{{Title: title, PlayedAt: playedAt}}, history.entries...)
+	if len(history.entries) > HISTORY_SIZE {
+		history.entries = history.entries[:HISTORY_SIZE]
+	}
+}
+
+// Newest first
+func (history *SongHistory) Entries() []HistoryEntry {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	return append([]HistoryEntry(nil), history.entries...)
+}
+
+// "just now" or "3 min ago", and the clock time once it's an hour old
+func relativeTime(then time.Time, now time.Time) string {
+	elapsed := now.Sub(then)
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%d min ago", int(elapsed/time.Minute))
+	}
+	return formatClock(then)
+}
+
+func showHistoryDialog(window fyne.Window, history *SongHistory) {
+	// Dialogs are drawn over the window tooltips, so they get their own
+	tooltips := NewTooltipLayer()
+	entries := history.Entries()
+
+	var content fyne.CanvasObject
+	var list *widget.List
+	if len(entries) == 0 {
+		content = widget.NewLabel("Nothing played yet")
+	} else {
+		list = widget.NewList(
+			func() int {
+				return len(entries)
+			},
+			func() fyne.CanvasObject {
+				title := widget.NewLabel("")
+				title.Truncation = fyne.TextTruncateEllipsis
+				return container.NewBorder(nil, nil, nil, NewTooltipLabel(tooltips, "", ""), title)
+			},
+			func(id widget.ListItemID, item fyne.CanvasObject) {
+				row := item.(*fyne.Container)
+				row.Objects[0].(*widget.Label).SetText(entries[id].Title)
+				playedAt := row.Objects[1].(*TooltipLabel)
+				playedAt.SetText(relativeTime(entries[id].PlayedAt, time.Now()))
+				playedAt.SetTooltip(entries[id].PlayedAt.Format("Monday ") + formatClockSeconds(entries[id].PlayedAt))
+			},
+		)
+		content = list
+	}
+
+	historyDialog := dialog.NewCustom("Recently played", "Close", tooltips.Wrap(content), window)
+	done := make(chan struct{})
+	historyDialog.SetOnClosed(func() {
+		close(done)
+	})
+	if list != nil {
+		go func() {
+			ticker := time.NewTicker(HISTORY_REFRESH_INTERVAL)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					queueUIUpdate(list.Refresh)
+				}
+			}
+		}()
+	}
+	historyDialog.Resize(fyne.NewSize(400, 400))
+	historyDialog.Show()
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Our HTTP clients for the station API and the album art. Between the poller,
+ * the track end timer, the art prefetching and the user asking for a refresh,
+ * several requests can go out at once, so they share a limit of requests in
+ * flight, to be nice to the station and to ourselves.
+ */
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Requests to the station and its art at the same time, the rest wait their turn
+const MAX_STATION_REQUESTS = 3
+
+// A request that hangs would keep its turn forever otherwise
+const STATION_REQUEST_TIMEOUT = 30 * time.Second
+
+var stationRequests = make(chan struct{}, MAX_STATION_REQUESTS)
+
+var apiClient = newStationClient(&networkUsage.API)
+var artClient = newStationClient(&networkUsage.Art)
+
+func newStationClient(counter *atomic.Int64) *http.Client {
+	return &http.Client{
+		Timeout: STATION_REQUEST_TIMEOUT,
+		Transport: &limitedTransport{
+			slots: stationRequests,
+			base:  &countingTransport{counter: counter},
+		},
+	}
+}
+
+// Takes a slot for each request, and gives it back once its body is closed
+type limitedTransport struct {
+	slots chan struct{}
+	base  http.RoundTripper
+}
+
+func (transport *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case transport.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := transport.base.RoundTrip(req)
+	if err != nil {
+		<-transport.slots
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { <-transport.slots }}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (body *releasingBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.releaseOnce.Do(body.release)
+	return err
+}
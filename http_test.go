@@ -0,0 +1,78 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStationRequestsLimit(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("no listening sockets on js/wasm")
+	}
+
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+		// Long enough for the other requests to pile up
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(w, "{}")
+	}))
+	defer server.Close()
+
+	var counter atomic.Int64
+	client := newStationClient(&counter)
+	var requests sync.WaitGroup
+	for i := 0; i < 4*MAX_STATION_REQUESTS; i++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	requests.Wait()
+
+	if highest := maxInFlight.Load(); highest > MAX_STATION_REQUESTS {
+		t.Errorf("%d requests in flight, the limit is %d", highest, MAX_STATION_REQUESTS)
+	} else if highest < MAX_STATION_REQUESTS {
+		t.Errorf("only %d requests in flight, they should use all %d slots", highest, MAX_STATION_REQUESTS)
+	}
+	if counter.Load() == 0 {
+		t.Error("the responses weren't counted")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * The window layout. Stacking everything works for the usual tall window, but
+ * on a wide one it wastes most of the space, so when the window is wide enough
+ * the card goes to the left and the rest stacks up on its right. It's all the
+ * same objects, just placed differently, so resizing never swaps any content.
+ */
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// How the window is laid out
+const (
+	LayoutAuto      = "auto"
+	LayoutPortrait  = "portrait"
+	LayoutLandscape = "landscape"
+)
+
+var LAYOUT_OPTIONS = []string{LayoutAuto, LayoutPortrait, LayoutLandscape}
+
+// In auto mode we go landscape when the window is this much wider than tall
+const LANDSCAPE_RATIO = 1.3
+
+type playerLayout struct {
+	// Goes on the left in landscape, in its place in the stack in portrait
+	card fyne.CanvasObject
+	mode string
+}
+
+func newPlayerLayout(card fyne.CanvasObject, mode string) *playerLayout {
+	return &playerLayout{card: card, mode: mode}
+}
+
+func (layout *playerLayout) SetMode(mode string) {
+	layout.mode = mode
+}
+
+// Visible objects, the card apart if we are going to place it on its own
+func visibleObjects(objects []fyne.CanvasObject, skip fyne.CanvasObject) []fyne.CanvasObject {
+	visible := make([]fyne.CanvasObject, 0, len(objects))
+	for _, object := range objects {
+		if object.Visible() && object != skip {
+			visible = append(visible, object)
+		}
+	}
+	return visible
+}
+
+// Size of the objects one on top of the other
+func stackMinSize(objects []fyne.CanvasObject) fyne.Size {
+	size := fyne.NewSize(0, 0)
+	for i, object := range objects {
+		min := object.MinSize()
+		size.Width = fyne.Max(size.Width, min.Width)
+		size.Height += min.Height
+		if i > 0 {
+			size.Height += theme.Padding()
+		}
+	}
+	return size
+}
+
+func stackObjects(objects []fyne.CanvasObject, pos fyne.Position, width float32) {
+	for _, object := range objects {
+		height := object.MinSize().Height
+		object.Move(pos)
+		object.Resize(fyne.NewSize(width, height))
+		pos.Y += height + theme.Padding()
+	}
+}
+
+func (layout *playerLayout) portraitMinSize(objects []fyne.CanvasObject) fyne.Size {
+	return stackMinSize(visibleObjects(objects, nil))
+}
+
+func (layout *playerLayout) landscapeMinSize(objects []fyne.CanvasObject) fyne.Size {
+	rest := stackMinSize(visibleObjects(objects, layout.card))
+	card := layout.card.MinSize()
+	return fyne.NewSize(card.Width+theme.Padding()+rest.Width, fyne.Max(card.Height, rest.Height))
+}
+
+func (layout *playerLayout) landscape(objects []fyne.CanvasObject, size fyne.Size) bool {
+	switch layout.mode {
+	case LayoutPortrait:
+		return false
+	case LayoutLandscape:
+		return true
+	}
+	return size.Width > size.Height*LANDSCAPE_RATIO && size.Width >= layout.landscapeMinSize(objects).Width
+}
+
+func (layout *playerLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	if !layout.landscape(objects, size) {
+		stackObjects(visibleObjects(objects, nil), fyne.NewPos(0, 0), size.Width)
+		return
+	}
+
+	cardWidth := layout.card.MinSize().Width
+	layout.card.Move(fyne.NewPos(0, 0))
+	layout.card.Resize(fyne.NewSize(cardWidth, size.Height))
+	restX := cardWidth + theme.Padding()
+	stackObjects(visibleObjects(objects, layout.card), fyne.NewPos(restX, 0), size.Width-restX)
+}
+
+// Small enough for the window to take either shape, unless the user picked one
+func (layout *playerLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	portrait := layout.portraitMinSize(objects)
+	landscape := layout.landscapeMinSize(objects)
+	switch layout.mode {
+	case LayoutPortrait:
+		return portrait
+	case LayoutLandscape:
+		return landscape
+	}
+	return fyne.NewSize(fyne.Min(portrait.Width, landscape.Width), fyne.Min(portrait.Height, landscape.Height))
+}
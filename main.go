@@ -36,15 +36,21 @@ package main
  */
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"image"
+	"io"
 	"log"
+	"math/rand"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -52,24 +58,110 @@ import (
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
 // Enums and constants
-const MAX_CHARS = 24
+// How long the play button rests after being pressed
+const PLAY_BUTTON_COOLDOWN = 500 * time.Millisecond
+
+// Retry delays when we can't get the stations at startup
+const STATIONS_RETRY_DELAY = 5 * time.Second
+const STATIONS_MAX_RETRY_DELAY = 5 * time.Minute
+
+// Below this buffer fill level we consider the stream to be starving
+const LOW_BUFFER_THRESHOLD = 0.2
+
+// How many consecutive low buffer readings before we widen the buffer
+const LOW_BUFFER_READINGS = 6
+
+// Card title while we aren't playing anything
+const NOT_PLAYING_TITLE = "Not playing"
+
+// Long live sets keep the same art for hours, after this long without new
+// art we go through the idle slideshow, one image every IDLE_ART_INTERVAL
+const IDLE_ART_AFTER = 10 * time.Minute
+const IDLE_ART_INTERVAL = 30 * time.Second
+
+// How often we check whether the auto-stop is due
+const AUTO_STOP_CHECK_INTERVAL = time.Minute
+
+// Title changes ask the station about the track at most this often
+const STATION_INFO_INTERVAL = 30 * time.Second
+
+// How long the stream takes to ease in after pressing play
+const FADE_IN_DURATION = 3 * time.Second
 
 const (
 	Loading int = iota
 	Playing
 	Stopped
+	Paused
 )
 
-// helper
-func check(err error) {
+func statusName(status int) string {
+	switch status {
+	case Loading:
+		return "Loading"
+	case Playing:
+		return "Playing"
+	case Stopped:
+		return "Stopped"
+	case Paused:
+		return "Paused"
+	}
+	return "Unknown"
+}
+
+// What the play button does: stop whatever is going on, or start the stream
+// with start. The status only changes if it worked.
+func togglePlayback(player RadioPlayer, status *int, start func() error) error {
+	if *status != Stopped {
+		// Buffering, reconnecting or playing, all the same: stop
+		player.Stop()
+		*status = Stopped
+		return nil
+	}
+
+	err := start()
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
+	*status = Loading
+	return nil
+}
+
+// Lets one press of a button through at a time, and none while the button
+// rests after it
+type pressGate struct {
+	mutex   sync.Mutex
+	resting bool
+}
+
+// Runs press unless another press is running or resting, then rests for
+// cooldown and calls rested. Returns whether press ran.
+func (gate *pressGate) run(cooldown time.Duration, press func(), rested func()) bool {
+	gate.mutex.Lock()
+	if gate.resting {
+		gate.mutex.Unlock()
+		return false
+	}
+	gate.resting = true
+	gate.mutex.Unlock()
+
+	defer time.AfterFunc(cooldown, func() {
+		gate.mutex.Lock()
+		gate.resting = false
+		gate.mutex.Unlock()
+		if rested != nil {
+			rested()
+		}
+	})
+	press()
+	return true
 }
 
 func initLogging() (*os.File, error) {
@@ -89,35 +181,67 @@ func main() {
 	// Here we store the current song, since we will be using in
 	// several places
 	var currentSong string
-	var currentSongScrollIndex int
 
 	// Logfile
 	var logFile *os.File
 
-	stations, err := fetchStations()
+	appRunning := true
 
-	check(err)
+	PLAYER_CMD := findPlayer()
 
-	currentStation := stations[0]
+	// Command line arguments parsing
+	loggingToFilePtr := flag.Bool("log", false, "Create a log file")
+	autoplayPtr := flag.Bool("autoplay", false, "Start playing as soon as the app opens")
+	playerCmdPtr := flag.String("ffmpeg", "", "Path to the ffmpeg binary to use")
+	metadataDirPtr := flag.String("metadata", "", "Read now playing info from nowplaying.json and schedule.json in this directory instead of the station API")
+	configPathPtr := flag.String("config", "", "Read the settings from this JSON config file")
+	controlLANPtr := flag.Bool("control-lan", false, "Make the control API and web page reachable from the local network")
+	externalPtr := flag.String("mpv", "", "Play through this mpv binary instead of ffmpeg")
+	silentPtr := flag.Bool("silent", false, "Decode the stream but don't play it, for working without an audio device")
+	trackLogPtr := flag.String("tracklog", "", "Add every track played to this CSV file")
+	teePtr := flag.String("tee", "", "Also write the decoded audio to this file or named pipe, as raw 16 bit 44.1 kHz stereo")
 
-	appRunning := true
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Commands for a running player: %s\n\n", strings.Join(CONTROL_COMMANDS, ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Given a command we are just the client of the player already running
+	if flag.NArg() > 0 {
+		os.Exit(runControlCommand(CONTROL_ADDRESS, flag.Args()))
+	}
 
-	PLAYER_CMD := "ffmpeg"
+	var err error
 
-	if runtime.GOOS == "windows" {
-		log.Println("Detected Windows")
-		ex, err := os.Executable()
+	config := &Config{}
+	if *configPathPtr != "" {
+		config, err = loadConfig(*configPathPtr)
 		if err != nil {
-			log.Fatal("Couldn't get executable path")
+			fmt.Printf("ERROR: Couldn't load the config file: %s\n", err)
+			os.Exit(1)
 		}
-		exPath := filepath.Dir(ex)
-		PLAYER_CMD = filepath.Join(exPath, "ffmpeg.exe")
-	}
 
-	// Command line arguments parsing
-	loggingToFilePtr := flag.Bool("log", false, "Create a log file")
+		// The flags given in the command line win over the file
+		flagsSet := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) {
+			flagsSet[f.Name] = true
+		})
+		if config.Log != nil && !flagsSet["log"] {
+			*loggingToFilePtr = *config.Log
+		}
+		if config.Autoplay != nil && !flagsSet["autoplay"] {
+			*autoplayPtr = *config.Autoplay
+		}
+		if config.FFmpeg != "" && !flagsSet["ffmpeg"] {
+			*playerCmdPtr = config.FFmpeg
+		}
+	}
 
-	flag.Parse()
+	if *playerCmdPtr != "" {
+		PLAYER_CMD = *playerCmdPtr
+	}
 
 	if *loggingToFilePtr {
 		logFile, err = initLogging()
@@ -128,128 +252,745 @@ func main() {
 
 	log.Println("Starting the app")
 
+	// If we can't get the stations (we're offline) we start anyway, and keep
+	// trying to get them in the background
+	stations, err := fetchStations()
+	if err != nil {
+		log.Println("Couldn't fetch the stations, will keep trying")
+	}
+
+	var currentStation StationInfo
+	if len(stations) > 0 {
+		currentStation = stations[0]
+	}
+
 	// Create the status channel, to read from StreamPlayer and the pipe to send commands to it
 	// pipe_chan := make(chan io.ReadCloser)
 
 	// Create our StreamPlayer instance
 	streamPlayer := StreamPlayer{player_name: PLAYER_CMD}
+	if *silentPtr {
+		streamPlayer.sink = &SilentSink{}
+	}
+	var trackLog *TrackLog
+	if *trackLogPtr != "" {
+		trackLog, err = OpenTrackLog(*trackLogPtr)
+		if err != nil {
+			fmt.Printf("ERROR: Couldn't open the track log: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	var teeSink *FileSink
+	if *teePtr != "" {
+		teeSink = &FileSink{Path: *teePtr}
+		streamPlayer.ExtraSinks = append(streamPlayer.ExtraSinks, teeSink)
+	}
+	if *externalPtr != "" {
+		streamPlayer.player_name = *externalPtr
+		streamPlayer.External = true
+	}
 
 	// Create our app and window
-	app := app.New()
-	window := app.NewWindow("RadioSpiral Player")
+	app := app.NewWithID("net.radiospiral.player")
+	prefs := app.Preferences()
+	setClockStyle(prefs.StringWithFallback(PREF_CLOCK, ClockAuto))
+	app.Settings().SetTheme(newScaledTheme(intPreference(prefs, PREF_UI_SCALE, UI_SCALE_RANGE)))
+	window := app.NewWindow(WINDOW_TITLE)
+
+	streamPlayer.deviceBufferSize = bufferSizePreference(prefs)
+	streamPlayer.HWAccel = prefs.Bool(PREF_HWACCEL)
+
+	// Where we get the now playing info from
+	newMetadataSource := func(station StationInfo) MetadataSource {
+		if *metadataDirPtr != "" {
+			return NewFileMetadataSource(*metadataDirPtr)
+		}
+		return NewHTTPMetadataSource(station, prefs.String(PREF_API_KEY), config.AnnouncementURL)
+	}
+	metadata := newMetadataSource(currentStation)
+
+	// The stream of the station, unless the user set their own (a mirror,
+	// a private stream...)
+	streamURL := func() string {
+		if config.StreamURL != "" {
+			return config.StreamURL
+		}
+		customURL := prefs.String(PREF_STREAM_URL)
+		if customURL != "" {
+			return customURL
+		}
+		return currentStation.ListenUrl
+	}
 
 	window.Resize(fyne.NewSize(400, 450))
-	window.SetIcon(resourceIconPng)
+	window.SetIcon(appIcon())
 
 	// Keep the status of the player
 	playStatus := Stopped
 
-	// Header section
-	radioSpiralHeaderImage := canvas.NewImageFromResource(resourceHeaderPng)
-	radioSpiralHeaderImage.SetMinSize(fyne.NewSize(400, 120))
-	radioSpiralHeaderImage.FillMode = canvas.ImageFillContain
+	// Icon only buttons get a tooltip drawn on this layer
+	tooltips := NewTooltipLayer()
 
-	// Placeholder avatar
-	radioSpiralAvatar := loadImageURL("https://radiospiral.net/wp-content/uploads/2018/03/Radio-Spiral-Logo-1.png")
+	// Header section, with the live badge on its corner
+	liveBadge := NewLiveBadge()
+	radioSpiralHeader := container.NewStack(
+		headerImage(),
+		container.NewVBox(container.NewHBox(layout.NewSpacer(), container.NewPadded(liveBadge))),
+	)
+
+	// Placeholder avatar, shown when there's no album art
+	radioSpiralAvatar := loadPlaceholder(prefs.String(PREF_PLACEHOLDER))
 
 	// Album cover section
-	radioSpiralCanvas := canvas.NewImageFromImage(radioSpiralAvatar)
-	radioSpiralCanvas.SetMinSize(fyne.NewSize(200, 200))
-	albumCard := widget.NewCard("Now playing", "", radioSpiralCanvas)
+	albumCard := widget.NewCard(NOT_PLAYING_TITLE, "", nil)
+	songMarquee := NewMarquee("")
 	centerCardContainer := container.NewCenter(albumCard)
 
+	// What the card should show, we keep it up to date even while the track
+	// info is hidden so we can show it right away when it is back
+	cardTitle := NOT_PLAYING_TITLE
+	var cardArt image.Image
+	// Shown instead of the art while the slideshow goes, nil otherwise
+	var idleArt image.Image
+	lastArtChange := time.Now()
+	hideTrackInfo := prefs.Bool(PREF_HIDE_TRACK_INFO)
+
+	// Now playing info and media keys of the OS
+	media := newMediaControls()
+
+	// Keep the system awake while we play, if the user wants to
+	inhibitor := newSleepInhibitor()
+	sleepInhibited := false
+	keepAwake := func(playing bool) {
+		active := playing && prefs.Bool(PREF_KEEP_AWAKE)
+		if active == sleepInhibited {
+			return
+		}
+		if active {
+			err := inhibitor.Inhibit()
+			if err != nil {
+				log.Printf("Couldn't keep the system awake: %s", err)
+				return
+			}
+		} else {
+			inhibitor.Release()
+		}
+		sleepInhibited = active
+	}
+
+	// Titles the user told us are junk (ads, "Unknown - Unknown"...) for
+	// this session, we show the station instead when they come up
+	suppressedTitles := make(map[string]bool)
+	trackInfoShown := func() bool {
+		return !hideTrackInfo && !suppressedTitles[currentSong]
+	}
+
+	var dismissTitleButton *TooltipButton
+	var saveArtButton *TooltipButton
+
+	// What the station API says is playing, it may know more than the
+	// stream title (album, genre...)
+	var nowPlayingSong SongInfo
+	// Whether the station API says there's a live show on
+	var stationLive bool
+
+	// What we know about the track with this stream title
+	songForTitle := func(title string) SongInfo {
+		if nowPlayingSong.Text == title {
+			return nowPlayingSong
+		}
+		// The API info is for another track, all we know is the title
+		song := SongInfo{Text: title}
+		song.Artist, song.Title = splitStreamTitle(title)
+		return song
+	}
+
+	// The track as the user wants it shown
+	displayTitle := func() string {
+		if currentSong == "" {
+			return ""
+		}
+		song := songForTitle(currentSong)
+		template := prefs.StringWithFallback(PREF_DISPLAY_TEMPLATE, DEFAULT_DISPLAY_TEMPLATE)
+		if title := renderDisplayTemplate(template, song); title != "" {
+			return title
+		}
+		return currentSong
+	}
+
+	// The card content stays, only the art in it changes
+	albumCanvas := canvas.NewImageFromImage(radioSpiralAvatar)
+	albumCanvas.SetMinSize(fyne.NewSize(200, 200))
+	// For debugging the metadata: the title line exactly as it came, quoted
+	// so stray spaces and control characters show
+	rawTitleLabel := widget.NewLabel("")
+	rawTitleLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	rawTitleLabel.Truncation = fyne.TextTruncateEllipsis
+	rawTitleLabel.Hidden = !prefs.Bool(PREF_SHOW_RAW_TITLE)
+	albumCard.SetContent(container.NewVBox(songMarquee, rawTitleLabel, albumCanvas))
+
+	refreshCard := func() {
+		queueUIUpdate(func() {
+			// What we show instead of the track, if anything
+			fallbackTitle := ""
+			if hideTrackInfo {
+				fallbackTitle = "RadioSpiral"
+			} else if suppressedTitles[currentSong] {
+				fallbackTitle = currentStation.Name
+			}
+
+			art := cardArt
+			if idleArt != nil {
+				art = idleArt
+			}
+			if art == nil || fallbackTitle != "" {
+				art = radioSpiralAvatar
+			}
+			if fallbackTitle != "" {
+				albumCard.SetTitle(fallbackTitle)
+				songMarquee.SetText("")
+			} else {
+				albumCard.SetTitle(cardTitle)
+				songMarquee.SetText(displayTitle())
+			}
+			albumCanvas.Image = art
+			albumCanvas.Refresh()
+
+			if fallbackTitle != "" || currentSong == "" {
+				dismissTitleButton.Disable()
+			} else {
+				dismissTitleButton.Enable()
+			}
+			// Only real art is worth saving, not our logo
+			if fallbackTitle != "" || cardArt == nil {
+				saveArtButton.Disable()
+			} else {
+				saveArtButton.Enable()
+			}
+
+			if fallbackTitle != "" {
+				window.SetTitle(windowTitle(""))
+				updateTaskbar(window, nil)
+			} else {
+				window.SetTitle(windowTitle(displayTitle()))
+				updateTaskbar(window, cardArt)
+			}
+
+			if playStatus == Stopped {
+				media.Clear()
+			} else if fallbackTitle != "" {
+				media.SetNowPlaying(fallbackTitle, "", nil)
+			} else {
+				artist, title := splitStreamTitle(currentSong)
+				media.SetNowPlaying(title, artist, cardArt)
+			}
+		})
+	}
+
+	dismissTitleButton = NewTooltipButton(tooltips, theme.ContentClearIcon(), "Dismiss this title", func() {
+		log.Printf("Suppressing title: %s", truncateTitle(currentSong, MAX_LOG_TITLE))
+		suppressedTitles[currentSong] = true
+		refreshCard()
+	})
+	saveArtButton = NewTooltipButton(tooltips, theme.DocumentSaveIcon(), "Save album art", func() {
+		// The art may change while the user picks where to save it
+		art := cardArt
+		if art == nil {
+			return
+		}
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			err = encodeImage(writer, art, writer.URI().Extension())
+			if err != nil {
+				log.Printf("Couldn't save the album art: %s", err)
+				dialog.ShowError(err, window)
+			}
+		}, window)
+		saveDialog.SetFileName(artFileName(displayTitle()))
+		saveDialog.Show()
+	})
+	refreshCard()
+
+	// Hosts can ask us to warn them when their stream goes quiet
+	deadAirLabel := widget.NewLabelWithStyle("Possible dead air", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	deadAirLabel.Importance = widget.DangerImportance
+	deadAirLabel.Hide()
+	silenceDetector := NewSilenceDetector(intPreference(prefs, PREF_SILENCE_THRESHOLD, SILENCE_THRESHOLD_RANGE), silenceDurationPreference(prefs))
+	silenceDetector.OnSilent = func() {
+		log.Println("The stream has been silent for a while, possible dead air")
+		queueUIUpdate(deadAirLabel.Show)
+		if prefs.Bool(PREF_NOTIFICATIONS) {
+			app.SendNotification(fyne.NewNotification("Possible dead air", currentStation.Name+" has gone quiet"))
+		}
+	}
+	silenceDetector.OnSound = func() {
+		queueUIUpdate(deadAirLabel.Hide)
+	}
+	// Read for every bit of audio, so we don't go through the preferences
+	var silenceAlert atomic.Bool
+	silenceAlert.Store(prefs.Bool(PREF_SILENCE_ALERT))
+	streamPlayer.OnAudioData = func(data []byte) {
+		if silenceAlert.Load() {
+			silenceDetector.Feed(data)
+		}
+	}
+
+	// Holds the title back while a broken encoder flips it around
+	titleGuard := &TitleFlapGuard{}
+
+	// Once stopped, whatever we were playing is not true anymore: back to the
+	// placeholder until we play again
+	clearNowPlaying := func() {
+		currentSong = ""
+		titleGuard.Reset()
+		nowPlayingSong = SongInfo{}
+		stationLive = false
+		cardTitle = NOT_PLAYING_TITLE
+		rawTitleLabel.SetText("")
+		liveBadge.Clear()
+		cardArt = nil
+		idleArt = nil
+		lastArtChange = time.Now()
+		silenceDetector.Reset()
+		refreshCard()
+	}
+
 	volumeBind := binding.BindFloat(&streamPlayer.currentVolume)
 	volumeBar := widget.NewProgressBarWithData(volumeBind)
 
 	// Player section
-	volumeDown := widget.NewButtonWithIcon("", theme.VolumeDownIcon(), func() {
-		streamPlayer.DecVolume()
-		volumeBind.Reload()
-	})
-	volumeUp := widget.NewButtonWithIcon("", theme.VolumeUpIcon(), func() {
-		streamPlayer.IncVolume()
-		volumeBind.Reload()
-	})
+	var volumeMute *TooltipButton
 
-	var volumeMute *widget.Button
+	// What the control API tells about us, pushed to its event stream each
+	// time the status, the title or the volume changes
+	// Everything that changes the state publishes it, so the snapshot is
+	// always up to date
+	playerState := NewPlayerState()
+	controlEvents := NewControlEvents()
+	publishStatus := func() {
+		if playStatus == Stopped {
+			playerState.SetFormat("")
+		}
+		playerState.Update(playStatus, CurrentTrackInfo{
+			Station: currentStation.Name,
+			Title:   currentSong,
+			Song:    nowPlayingSong,
+		}, streamPlayer.currentVolume)
+		controlEvents.Publish(playerState.ControlStatus())
+	}
 
-	volumeMute = widget.NewButtonWithIcon("", theme.VolumeMuteIcon(), func() {
-		streamPlayer.Mute()
+	// Reflect the mute state on its button, the volume can also reach
+	// zero with the volume down button, so check it after every change
+	updateVolumeControls := func() {
 		if streamPlayer.IsMuted() {
-			volumeMute.SetText("x")
+			volumeMute.SetIcon(theme.VolumeMuteIcon())
+			volumeMute.SetTooltip("Unmute")
 		} else {
-			volumeMute.SetText("")
+			volumeMute.SetIcon(theme.VolumeUpIcon())
+			volumeMute.SetTooltip("Mute")
 		}
 		volumeBind.Reload()
+		publishStatus()
+	}
+
+	// Anything the user does shows there's someone listening, and holds off
+	// the auto-stop
+	lastInteraction := time.Now()
+	userActive := func() {
+		lastInteraction = time.Now()
+	}
+
+	// Errors we can go on playing with show up for a moment on top of the window
+	toasts := NewToastStack()
+	showToast := func(message string) {
+		toasts.Push(message)
+	}
+
+	// The user changed the volume: update the controls and show it for a moment
+	volumeOSD := NewVolumeOSD()
+	volumeChanged := func() {
+		// Muting isn't a volume to come back to, and without a stream the
+		// volume didn't change
+		if streamPlayer.output != nil && !streamPlayer.IsMuted() {
+			prefs.SetFloat(PREF_VOLUME, streamPlayer.currentVolume)
+		}
+		updateVolumeControls()
+		volumeOSD.Flash(streamPlayer.currentVolume, streamPlayer.IsMuted())
+	}
+
+	volumeDown := NewTooltipButton(tooltips, theme.VolumeDownIcon(), "Volume down", func() {
+		userActive()
+		streamPlayer.DecVolume()
+		volumeChanged()
+	})
+	volumeUp := NewTooltipButton(tooltips, theme.VolumeUpIcon(), "Volume up", func() {
+		userActive()
+		streamPlayer.IncVolume()
+		volumeChanged()
+	})
+
+	volumeMute = NewTooltipButton(tooltips, theme.VolumeUpIcon(), "Mute", func() {
+		userActive()
+		streamPlayer.Mute()
+		volumeChanged()
 	})
 
-	volumeTop := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+	volumeTop := NewTooltipButton(tooltips, theme.ViewRefreshIcon(), "Full volume", func() {
+		userActive()
 		streamPlayer.SetVolume(1.0)
 		streamPlayer.currentVolume = 1.0
-		volumeBind.Reload()
+		volumeChanged()
 	})
 
-	// Station selector
-	var stationSelect *widget.Select
-	stationNames := make([]string, len(stations))
+	// The play button, reconnections and station changes all start and stop
+	// the stream, and not always from the same goroutine
+	var playbackMutex sync.Mutex
 
-	for i, elem := range stations {
-		stationNames[i] = elem.Name
+	// Start the stream from scratch, at the volume of the config file if it
+	// has one, or the last one the user set
+	startStream := func() error {
+		volume := prefs.FloatWithFallback(PREF_VOLUME, DEFAULT_VOLUME)
+		if config.Volume != nil {
+			volume = *config.Volume
+		}
+		err := streamPlayer.Load(streamURL(), volume)
+		if err != nil {
+			return err
+		}
+		streamPlayer.Play()
+		return nil
 	}
 
-	stationSelect = widget.NewSelect(stationNames,
+	// Tear down the current stream and start it again, keeping the volume
+	reloadStream := func() {
+		playbackMutex.Lock()
+		defer playbackMutex.Unlock()
+
+		volume := streamPlayer.currentVolume
+		streamPlayer.Stop()
+		err := streamPlayer.Load(streamURL(), volume)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		streamPlayer.Play()
+		updateVolumeControls()
+	}
+
+	// Station selector
+	var stationSelect *widget.Select
+
+	stationSelect = widget.NewSelect([]string{},
 		func(r string) {
 			idx := stationSelect.SelectedIndex()
 			currentStation = stations[idx]
+			streamPlayer.SetVolumeOffset(stationVolumeOffset(prefs, currentStation))
+			metadata = newMetadataSource(currentStation)
+			prefs.SetString(PREF_LAST_STATION, currentStation.Shortcode)
+			publishStatus()
 
 			if streamPlayer.IsPlaying() {
-				volume := streamPlayer.GetVolume()
-				streamPlayer.Stop()
-				streamPlayer.Load(currentStation.ListenUrl)
-				streamPlayer.Play()
-				streamPlayer.SetVolume(volume)
-				volumeBind.Reload()
+				reloadStream()
 			}
 		})
 
-	stationSelect.SetSelectedIndex(0)
-	stationSelect.Resize(fyne.NewSize(300, 20))
+	setStations := func(newStations []StationInfo) {
+		stations = newStations
+		stationNames := make([]string, len(stations))
 
-	if len(stations) == 1 {
-		// No need to show extra stations if they are not present
-		stationSelect.Hide()
+		for i, elem := range stations {
+			stationNames[i] = elem.Name
+		}
+
+		stationSelect.SetOptions(stationNames)
+		if len(stations) > 0 {
+			// Pick up where we left it last time, if the station is still there
+			selected := 0
+			lastStation := prefs.String(PREF_LAST_STATION)
+			for i, elem := range stations {
+				if elem.Shortcode == lastStation {
+					selected = i
+				}
+			}
+			stationSelect.SetSelectedIndex(selected)
+		}
+
+		if len(stations) <= 1 {
+			// No need to show extra stations if they are not present
+			stationSelect.Hide()
+		} else {
+			stationSelect.Show()
+		}
 	}
 
+	setStations(stations)
+	stationSelect.Resize(fyne.NewSize(300, 20))
+
 	// Play button
-	var playButton *widget.Button
+	var playButton *TooltipButton
+	var pauseButton *TooltipButton
+	var skipToLiveButton *widget.Button
+
+	// The pause button resumes when paused, and skipping to live only makes
+	// sense when paused
+	updatePauseControls := func() {
+		if playStatus == Paused {
+			pauseButton.SetIcon(theme.MediaPlayIcon())
+			pauseButton.SetTooltip("Resume")
+			skipToLiveButton.Show()
+		} else {
+			pauseButton.SetIcon(theme.MediaPauseIcon())
+			pauseButton.SetTooltip("Pause")
+			skipToLiveButton.Hide()
+		}
+	}
+
+	// Pressing play tries the audio device again, but oto rarely gets a
+	// second chance in the same process, so we offer to start over
+	showAudioDeviceError := func(err error) {
+		var deviceErr *AudioDeviceError
+		if !errors.As(err, &deviceErr) {
+			dialog.ShowError(err, window)
+			return
+		}
+
+		message := widget.NewLabel(deviceErr.Advice())
+		message.Wrapping = fyne.TextWrapWord
+		deviceDialog := dialog.NewCustomConfirm("Audio device unavailable", "Restart", "Close", message, func(restart bool) {
+			if !restart {
+				return
+			}
+			err := restartPlayer()
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			app.Quit()
+		}, window)
+		deviceDialog.Resize(fyne.NewSize(360, 0))
+		deviceDialog.Show()
+	}
 
-	playButton = widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+	// Only pressing play fades in, reconnecting picks up where we were
+	fadeInPending := false
+	var playGate pressGate
+	var playButtonPressed func()
+	playButton = NewTooltipButton(tooltips, theme.MediaPlayIcon(), "Play/Stop", func() {
 		// Here we control each time the button is pressed and update its
 		// appearance anytime it is clicked. We make the player start playing
-		// or pause.
-		if !streamPlayer.IsPlaying() {
+		// or stop. Starting and stopping take a moment (ffmpeg has to start
+		// or be reaped), so the button rests a bit after each press and
+		// mashing it can't pile up transitions.
+		userActive()
+		playGate.run(PLAY_BUTTON_COOLDOWN, playButtonPressed, playButton.Enable)
+	})
+	playButtonPressed = func() {
+		playButton.Disable()
+
+		playbackMutex.Lock()
+		defer playbackMutex.Unlock()
+
+		err := togglePlayback(&streamPlayer, &playStatus, func() error {
+			// Without ffmpeg there's nothing we can play, tell the user
+			// instead of failing silently
+			err := checkPlayerAvailable(streamPlayer.player_name)
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			fadeInPending = prefs.Bool(PREF_FADE_IN)
+			if fadeInPending {
+				streamPlayer.HoldForFade()
+			}
+			return startStream()
+		})
+		if err != nil {
+			showAudioDeviceError(err)
+			return
+		}
+
+		if playStatus == Stopped {
+			playButton.SetIcon(theme.MediaPlayIcon())
+			playButton.SetText("")
+			updatePauseControls()
+			clearNowPlaying()
+		} else {
 			playButton.SetIcon(theme.MediaStopIcon())
 			playButton.SetText("(Buffering)")
-			streamPlayer.Load(currentStation.ListenUrl)
-			streamPlayer.Play()
-			playStatus = Loading
-		} else {
+		}
+		updateVolumeControls()
+		media.SetPlaying(playStatus != Stopped)
+		keepAwake(playStatus != Stopped)
+		publishStatus()
+	}
+
+	playButton.Importance = widget.HighImportance
+
+	pauseButton = NewTooltipButton(tooltips, theme.MediaPauseIcon(), "Pause", func() {
+		userActive()
+		if playStatus == Paused {
+			streamPlayer.Resume()
+			playStatus = Playing
+		} else if playStatus == Playing {
+			streamPlayer.Pause()
+			playStatus = Paused
+		}
+		updatePauseControls()
+		media.SetPlaying(playStatus == Playing)
+		keepAwake(playStatus == Playing)
+		publishStatus()
+	})
+
+	// After a long pause what we have buffered is old, start over from the
+	// live stream instead
+	skipToLiveButton = widget.NewButtonWithIcon("Skip to live", theme.MediaFastForwardIcon(), func() {
+		playStatus = Loading
+		playButton.SetText("(Buffering)")
+		publishStatus()
+		reloadStream()
+		updatePauseControls()
+	})
+	updatePauseControls()
+
+	// When the stream plays but sounds wrong, a fresh connection in one go
+	reloadButton := NewTooltipButton(tooltips, theme.MediaReplayIcon(), "Reload the stream", func() {
+		userActive()
+		if playStatus == Stopped {
+			return
+		}
+		log.Println("Reloading the stream")
+		playStatus = Loading
+		playButton.SetText("(Buffering)")
+		publishStatus()
+		reloadStream()
+		updatePauseControls()
+	})
+
+	// The media keys act like the buttons would
+	handleMediaCommand := func(command MediaCommand) {
+		switch command {
+		case MediaPlay:
+			if playStatus == Paused {
+				pauseButton.OnTapped()
+			} else if playStatus == Stopped && !playButton.Disabled() {
+				playButton.OnTapped()
+			}
+		case MediaPause:
 			if playStatus == Playing {
-				playStatus = Stopped
-				playButton.SetIcon(theme.MediaPlayIcon())
-				streamPlayer.Stop()
-			} else {
-				playStatus = Loading
-				playButton.SetText("(Buffering)")
-				playButton.SetIcon(theme.MediaStopIcon())
-				streamPlayer.Load(currentStation.ListenUrl)
-				streamPlayer.Play()
+				pauseButton.OnTapped()
+			}
+		case MediaTogglePlayPause:
+			if playStatus == Playing || playStatus == Paused {
+				pauseButton.OnTapped()
+			} else if playStatus == Stopped && !playButton.Disabled() {
+				playButton.OnTapped()
+			}
+		case MediaStop:
+			if playStatus == Playing || playStatus == Paused {
+				playButton.OnTapped()
 			}
+		case MediaVolumeUp:
+			volumeUp.OnTapped()
+		case MediaVolumeDown:
+			volumeDown.OnTapped()
+		case MediaMute:
+			volumeMute.OnTapped()
 		}
-		volumeBind.Reload()
-	})
+	}
+	media.OnCommand(handleMediaCommand)
 
-	playButton.Importance = widget.HighImportance
+	// Without MPRIS nobody tells us about the media keys, we can read them
+	// ourselves
+	mediaKeys := &MediaKeyListener{}
+	updateMediaKeys := func() {
+		if !prefs.Bool(PREF_MEDIA_KEYS) {
+			mediaKeys.Stop()
+			return
+		}
+		err := mediaKeys.Start(handleMediaCommand)
+		if err != nil {
+			log.Printf("Couldn't read the media keys: %s", err)
+			showToast("Couldn't read the media keys: " + err.Error())
+		}
+	}
+	updateMediaKeys()
+
+	// Scripts and hotkeys control us through the local API
+	controlAddress := CONTROL_ADDRESS
+	if *controlLANPtr {
+		controlAddress = CONTROL_LAN_ADDRESS
+		fmt.Println("WARNING: Anyone on your network can control the player at port 8957")
+		log.Println("Control API open to the local network")
+	}
+	controlServer, err := startControlServer(controlAddress, ControlHandlers{
+		Play: func() {
+			handleMediaCommand(MediaPlay)
+		},
+		Stop: func() {
+			handleMediaCommand(MediaStop)
+		},
+		SetVolume: func(volume float64) error {
+			if !streamPlayer.IsPlaying() {
+				return errors.New("Not playing")
+			}
+			userActive()
+			streamPlayer.SetVolume(volume)
+			streamPlayer.currentVolume = volume
+			volumeChanged()
+			return nil
+		},
+		Status: playerState.ControlStatus,
+	}, controlEvents)
+	if err != nil {
+		log.Printf("Couldn't start the control API: %s", err)
+		showToast("Couldn't start the control API: " + err.Error())
+	}
+
+	// Start playing as soon as we can if asked to
+	autoplay := *autoplayPtr || prefs.Bool(PREF_AUTOPLAY)
+	startAutoplay := func() {
+		if autoplay && !streamPlayer.IsPlaying() {
+			log.Println("Autoplay")
+			playButton.OnTapped()
+		}
+	}
+
+	// Nothing to play until we know the stations
+	if len(stations) == 0 {
+		playButton.Disable()
+		go func() {
+			rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+			for attempt := 0; appRunning; attempt++ {
+				time.Sleep(backoffDelay(attempt, STATIONS_RETRY_DELAY, STATIONS_MAX_RETRY_DELAY, rng))
+				newStations, err := fetchStations()
+				if err == nil && len(newStations) > 0 {
+					log.Println("Got the stations")
+					setStations(newStations)
+					playButton.Enable()
+					startAutoplay()
+					return
+				}
+			}
+		}()
+	}
+
+	// Buffer health, to help understand dropouts on flaky connections
+	bufferLow := false
+	bufferBar := widget.NewProgressBar()
+	bufferBar.TextFormatter = func() string {
+		if bufferLow {
+			return "Buffer low"
+		}
+		return "Buffer"
+	}
 
 	volumeContainer := container.NewBorder(
 		nil,
@@ -267,73 +1008,466 @@ func main() {
 		volumeBar,
 	)
 
-	// Process the output of ffmpeg here in a separate goroutine
-	go func() {
-		var scanner *bufio.Scanner
-		for {
-			if streamPlayer.out != nil {
-				scanner = bufio.NewScanner(streamPlayer.out)
-			} else {
-				time.Sleep(20 * time.Millisecond)
-				continue
+	// How many are listening with us, with the details in the tooltip
+	listenersLabel := NewTooltipLabel(tooltips, "", "")
+	listenersLabel.Importance = widget.LowImportance
+
+	artCache := NewArtCache(artDeadlinePreference(prefs))
+	artCache.SetLowData(prefs.Bool(PREF_LOW_DATA))
+	var prefetchTimer *time.Timer
+	var trackEndTimer *time.Timer
+
+	// Fetch the station info and show it on the card
+	var updateStationInfo func()
+	// Only tell about the station info failing once, not on every query
+	stationInfoFailing := false
+	updateStationInfo = func() {
+		stationData, err := metadata.NowPlaying()
+		// The API query gives nothing back, not even an error, when it
+		// can't reach the station
+		if err != nil || stationData == nil {
+			log.Println("Received error")
+			stationLive = false
+			liveBadge.Clear()
+			if !stationInfoFailing {
+				showToast("Couldn't get the station info, the card may be out of date")
 			}
-			for scanner.Scan() {
-				line := scanner.Text()
-				// Log, if enabled, the output of StreamPlayer
-				if *loggingToFilePtr {
-					log.Print("[" + streamPlayer.player_name + "] " + line)
-				}
-				if strings.Contains(line, "Output #0") {
-					playStatus = Playing
-					playButton.SetText("")
+			stationInfoFailing = true
+			return
+		}
+		stationInfoFailing = false
+
+		nowPlayingSong = stationData.NowPlaying.Song
+		publishStatus()
+		stationLive = stationData.Live.IsLive
+		liveBadge.SetLive(stationData.Live.IsLive)
+
+		listeners := stationData.Listeners
+		listenersLabel.SetText(fmt.Sprintf("%d listening", listeners.Current))
+		listenersLabel.SetTooltip(fmt.Sprintf("%d listening now\n%d unique listeners\n%d connections in total",
+			listeners.Current, listeners.Unique, listeners.Total))
+
+		// Cover art retrieval
+		if stationData.Live.IsLive {
+			cardTitle = "Live Show"
+		} else {
+			cardTitle = "Now playing"
+		}
+		coverArtURL := selectArtURL(stationData)
+		log.Printf("Received %s as art", coverArtURL)
+
+		previousArt := cardArt
+		cardArt = nil
+		if len(coverArtURL) > 0 {
+			log.Println("Fetching album art")
+			img, err := artCache.Get(coverArtURL)
+			if err == ErrLowData {
+				log.Println("Low data mode, no album art")
+			} else if err != nil {
+				log.Printf("Couldn't fetch album art: %s", err)
+				// Once the host is down we already told about it
+				if !errors.Is(err, ErrArtHostDown) {
+					showToast("Couldn't get the album art")
 				}
-				// Check if there's an updated title and reflect it on the
-				// GUI
-				if strings.Contains(line, "StreamTitle: ") {
-					log.Println("Found new stream title, updating GUI")
-					newTitleParts := strings.Split(line, "StreamTitle: ")
-					currentSong = newTitleParts[1]
-					currentSongScrollIndex = 0
-					albumCard.SetSubTitle(fmt.Sprintf("%.*s", MAX_CHARS, currentSong))
-					stationData, err := queryStation(currentStation)
-					if err != nil {
-						log.Println("Received error")
-						continue
-					}
+			} else {
+				cardArt = img
+			}
+		}
+		// Fresh art ends the slideshow
+		if cardArt != previousArt {
+			lastArtChange = time.Now()
+			idleArt = nil
+		}
+		refreshCard()
 
-					// Cover art retrieval
-					var coverArtURL string
-					if stationData.Live.IsLive {
-						log.Printf("Received %s as art", stationData.Live.Art)
-						albumCard.SetTitle("Live Show")
-						coverArtURL = stationData.Live.Art
-					} else {
-						log.Printf("Received %s as art", stationData.NowPlaying.Song.Art)
-						albumCard.SetTitle("Now playing")
-						coverArtURL = stationData.NowPlaying.Song.Art
+		// Get the art of the next track ready just before it starts. We ask
+		// again by then, as the queued track may have changed.
+		if prefetchTimer != nil {
+			prefetchTimer.Stop()
+		}
+		if !stationData.Live.IsLive && !prefs.Bool(PREF_LOW_DATA) {
+			untilPrefetch := time.Duration(stationData.NowPlaying.Remaining)*time.Second - ART_PREFETCH_LEAD
+			if untilPrefetch <= 0 {
+				if len(stationData.PlayingNext.Song.Art) > 0 {
+					artCache.Prefetch(stationData.PlayingNext.Song.Art)
+				}
+			} else {
+				prefetchTimer = time.AfterFunc(untilPrefetch, func() {
+					nextData, err := metadata.NowPlaying()
+					if err == nil && nextData != nil && len(nextData.PlayingNext.Song.Art) > 0 {
+						artCache.Prefetch(nextData.PlayingNext.Song.Art)
 					}
+				})
+			}
+		}
 
-					if len(coverArtURL) > 0 {
-						log.Println("Fetching album art")
-						albumImg := loadImageURL(coverArtURL)
-						albumCanvas := canvas.NewImageFromImage(albumImg)
-						albumCanvas.SetMinSize(fyne.NewSize(200, 200))
-						albumCard.SetContent(albumCanvas)
-					} else {
-						albumCanvas := canvas.NewImageFromImage(radioSpiralAvatar)
-						albumCanvas.SetMinSize(fyne.NewSize(200, 200))
-						albumCard.SetContent(albumCanvas)
-					}
+		// Ask again right after the track should end, so the card changes at
+		// the track boundary even when the stream doesn't send titles
+		if trackEndTimer != nil {
+			trackEndTimer.Stop()
+		}
+		if !stationData.Live.IsLive && stationData.NowPlaying.Remaining > 0 && !prefs.Bool(PREF_LOW_DATA) {
+			untilTrackEnd := time.Duration(stationData.NowPlaying.Remaining)*time.Second + TRACK_END_DELAY
+			trackEndTimer = time.AfterFunc(untilTrackEnd, func() {
+				if playStatus == Playing {
+					updateStationInfo()
 				}
+			})
+		}
+	}
+
+	// The next show on the schedule, with the one on air in the tooltip
+	scheduleLabel := NewTooltipLabel(tooltips, "", "")
+	scheduleLabel.Alignment = fyne.TextAlignCenter
+	scheduleLabel.Hide()
+
+	// The countdown ticks every second, so we keep what it needs around
+	var nextShowName string
+	var nextShowStart time.Time
+	updateCountdown := func() {
+		scheduleLabel.SetText(fmt.Sprintf("Up next: %s in %s", nextShowName, formatCountdown(time.Until(nextShowStart))))
+	}
+
+	updateSchedule := func() {
+		shows, err := metadata.Schedule()
+		if err != nil {
+			log.Printf("Couldn't get the schedule: %s", err)
+			return
+		}
+
+		sortSchedule(shows)
+		now := time.Now()
+		next := nextShow(shows, now)
+		if next == nil {
+			nextShowStart = time.Time{}
+			scheduleLabel.Hide()
+			return
+		}
+		nextShowName = next.DisplayName()
+		nextShowStart = time.Unix(int64(next.StartTime), 0)
+		updateCountdown()
+		tooltip := "Starts at " + formatClock(nextShowStart)
+		if current := currentShow(shows, now); current != nil {
+			tooltip += "\nOn air: " + current.DisplayName()
+		}
+		scheduleLabel.SetTooltip(tooltip)
+		scheduleLabel.Show()
+	}
+	go updateSchedule()
+
+	// Station announcements, once dismissed we don't show them again
+	var announcement *Announcement
+	announcementLabel := widget.NewLabel("")
+	announcementLabel.Wrapping = fyne.TextWrapWord
+	var announcementBanner *fyne.Container
+	announcementBanner = container.NewBorder(nil, nil, nil,
+		NewTooltipButton(tooltips, theme.CancelIcon(), "Dismiss", func() {
+			if announcement != nil {
+				prefs.SetString(PREF_LAST_ANNOUNCEMENT, announcement.Id)
+			}
+			announcementBanner.Hide()
+		}),
+		announcementLabel,
+	)
+	announcementBanner.Hide()
+
+	updateAnnouncement := func() {
+		var err error
+		announcement, err = metadata.Announcement()
+		if err != nil {
+			log.Printf("Couldn't get the announcement: %s", err)
+			return
+		}
+
+		if announcement == nil || announcement.Id == prefs.String(PREF_LAST_ANNOUNCEMENT) {
+			announcementBanner.Hide()
+			return
+		}
+		announcementLabel.SetText(announcement.Message)
+		announcementBanner.Show()
+	}
+	go updateAnnouncement()
+
+	// Between title changes we still check the station every now and then, as
+	// the live show and its art can change without a new stream title
+	pollInterval := func() time.Duration {
+		interval := config.PollIntervalOr(POLL_INTERVAL)
+		if prefs.Bool(PREF_LOW_DATA) {
+			interval *= LOW_DATA_POLL_FACTOR
+		}
+		return interval
+	}
+	stationPoller := NewPoller(pollInterval(), rand.New(rand.NewSource(time.Now().UnixNano())), func() {
+		updateSchedule()
+		updateAnnouncement()
+		if playStatus == Playing {
+			updateStationInfo()
+		}
+	})
+	stationPoller.Start()
+
+	// R checks the station right away, handy during live shows. The window
+	// only gets the keys no widget takes, but make sure we aren't typing
+	window.Canvas().SetOnTypedRune(func(r rune) {
+		if window.Canvas().Focused() != nil {
+			return
+		}
+		switch r {
+		case 'r', 'R':
+			log.Println("Checking the station now")
+			stationPoller.Refresh()
+		}
+	})
+
+	// Once the next show starts the schedule and what's playing have moved on,
+	// no need to wait for the poller to find out
+	go func() {
+		for appRunning {
+			time.Sleep(time.Second)
+			if nextShowStart.IsZero() {
+				continue
+			}
+			if time.Now().Before(nextShowStart) {
+				updateCountdown()
+				continue
+			}
+
+			nextShowStart = time.Time{}
+			updateSchedule()
+			if playStatus == Playing {
+				updateStationInfo()
+			}
+		}
+	}()
+
+	// What ffmpeg tells us about the stream
+	outputParser := NewOutputParser()
+	if *loggingToFilePtr {
+		outputParser.LogPrefix = "[" + streamPlayer.player_name + "] "
+	}
+	// If the audio stops coming ffmpeg is either gone or broken, make sure it
+	// is gone so the output loop below sees the stream ended and reconnects
+	streamPlayer.OnAudioEnd = func() {
+		log.Println("The audio stream ended")
+		streamPlayer.KillDecoder()
+	}
+
+	// Failed attempts to get the stream back since it last played, and the
+	// last thing ffmpeg complained about
+	reconnectAttempt := 0
+	lastStreamError := NoStreamError
+	reconnectRng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// We are playing once the sink gets the first audio, before that oto is
+	// still filling its buffer and there's nothing to hear
+	startedPlaying := func() {
+		// The user may have stopped it meanwhile
+		if playStatus != Loading {
+			return
+		}
+		playStatus = Playing
+		reconnectAttempt = 0
+		lastStreamError = NoStreamError
+		playButton.SetText("")
+		if fadeInPending {
+			fadeInPending = false
+			streamPlayer.FadeIn(FADE_IN_DURATION)
+		}
+		silenceDetector.Reset()
+		media.SetPlaying(true)
+		keepAwake(true)
+		publishStatus()
+	}
+	streamPlayer.OnAudioStart = startedPlaying
+	// Where the fade ends up may not be where it started, if the user
+	// changed the volume meanwhile
+	streamPlayer.OnFadeEnd = updateVolumeControls
+	// mpv plays on its own, all we know is when it opens the audio device
+	outputParser.OnPlaying = func() {
+		// ffmpeg describes the input before starting its output
+		playerState.SetFormat(outputParser.StreamFormat())
+		if streamPlayer.External {
+			startedPlaying()
+		}
+	}
+
+	outputParser.OnError = func(streamError StreamError, line string) {
+		log.Printf("Stream error: %s", streamError)
+		lastStreamError = streamError
+		if streamError == ConnectionLost && playStatus == Playing {
+			// Don't wait for ffmpeg to notice, the output loop will see it
+			// ending and reconnect
+			playStatus = Loading
+			playButton.SetText("(Connection lost)")
+			publishStatus()
+			streamPlayer.KillDecoder()
+		}
+	}
+
+	// ffmpeg ended without us asking, so the stream dropped. Try to get it back
+	// a few times before giving up.
+	reconnect := func() {
+		settings := reconnectPreference(prefs)
+		if reconnectAttempt >= settings.MaxRetries {
+			log.Printf("Stream lost, giving up after %d attempts", reconnectAttempt)
+			reconnectAttempt = 0
+			playStatus = Stopped
+			playButton.SetText("")
+			playButton.SetIcon(theme.MediaPlayIcon())
+			streamPlayer.Stop()
+			clearNowPlaying()
+			keepAwake(false)
+			publishStatus()
+			message := "Lost the connection to the stream"
+			if lastStreamError != NoStreamError {
+				message = lastStreamError.String()
 			}
-			if err := scanner.Err(); err != nil {
-				log.Println("FFMpeg stream not ready or ended. Waiting before restarting")
-				time.Sleep(200 * time.Millisecond)
-				scanner = bufio.NewScanner(streamPlayer.out)
+			queueUIUpdate(func() {
+				dialog.ShowError(errors.New(message), window)
+			})
+			return
+		}
+
+		delay := backoffDelay(reconnectAttempt, settings.BaseDelay, settings.MaxDelay, reconnectRng)
+		reconnectAttempt += 1
+		log.Printf("Stream lost, reconnecting in %s (attempt %d of %d)", delay, reconnectAttempt, settings.MaxRetries)
+		showToast(fmt.Sprintf("Lost the stream, reconnecting (attempt %d of %d)", reconnectAttempt, settings.MaxRetries))
+		playStatus = Loading
+		playButton.SetText("(Reconnecting)")
+		publishStatus()
+		time.Sleep(delay)
+
+		// The user may have stopped it meanwhile
+		if playStatus == Loading {
+			reloadStream()
+		}
+	}
+	// Some stations change the title every few seconds, and asking the
+	// station each time (art included) is a lot of work for little. Unless
+	// told otherwise we ask in the background, at most once in a while, and
+	// the track end timer keeps the card on time for regular tracks.
+	var stationInfoMutex sync.Mutex
+	stationInfoPending := false
+	var lastStationQuery time.Time
+	requestStationInfo := func() {
+		stationInfoMutex.Lock()
+		defer stationInfoMutex.Unlock()
+		if stationInfoPending {
+			return
+		}
+		stationInfoPending = true
+		wait := max(time.Until(lastStationQuery.Add(STATION_INFO_INTERVAL)), 0)
+		time.AfterFunc(wait, func() {
+			stationInfoMutex.Lock()
+			stationInfoPending = false
+			lastStationQuery = time.Now()
+			stationInfoMutex.Unlock()
+			if playStatus == Playing {
+				updateStationInfo()
 			}
+		})
+	}
+	outputParser.OnRawTitle = func(line string) {
+		queueUIUpdate(func() {
+			rawTitleLabel.SetText(fmt.Sprintf("%q", strings.TrimSpace(line)))
+		})
+	}
+	songHistory := &SongHistory{}
+	showTitle := func(title string) {
+		currentSong = title
+		songHistory.Add(title, time.Now())
+		if trackInfoShown() {
+			songMarquee.SetText(displayTitle())
+		} else {
+			refreshCard()
+		}
+		if prefs.Bool(PREF_NOTIFICATIONS) && trackInfoShown() {
+			app.SendNotification(fyne.NewNotification("Now playing", truncateTitle(displayTitle(), MAX_NOTIFICATION_TITLE)))
+		}
+		publishStatus()
+		if prefs.Bool(PREF_QUERY_EVERY_TITLE) {
+			updateStationInfo()
+		} else {
+			requestStationInfo()
+		}
+		// One line per title change, with what the station says about the
+		// track only when it's about this one
+		if trackLog != nil {
+			err := trackLog.Log(time.Now(), songForTitle(title), stationLive, title)
+			if err != nil {
+				log.Printf("Couldn't write to the track log: %s", err)
+			}
+		}
+	}
+	titleGuard.OnUnstable = func() {
+		log.Println("The stream title keeps changing, ignoring it until it settles")
+		queueUIUpdate(func() {
+			songMarquee.SetText("Stream title unstable, waiting for it to settle")
+		})
+	}
+	titleGuard.OnSettled = func(title string) {
+		if playStatus == Stopped {
+			return
+		}
+		showTitle(title)
+	}
+	outputParser.OnTitle = func(title string) {
+		if titleGuard.Change(title, time.Now()) {
+			showTitle(title)
+		}
+	}
+
+	// Process the output of ffmpeg here in a separate goroutine
+	go func() {
+		for {
+			out := streamPlayer.out
+			decoderDone := streamPlayer.DecoderDone()
+			if out == nil {
+				time.Sleep(20 * time.Millisecond)
+				continue
+			}
+			// Closed by the player when it stops, nothing to report then
+			if err := outputParser.Process(out); err != nil && !errors.Is(err, os.ErrClosed) {
+				log.Printf("Couldn't read the output of ffmpeg: %s", err)
+				// ffmpeg blocks if nobody reads what it says
+				io.Copy(io.Discard, out)
+			}
+			// The output can end before ffmpeg does, it's only gone once it
+			// exited
+			if decoderDone != nil {
+				<-decoderDone
+			}
+			// If the output we were reading is still the current one, the player
+			// didn't close it, ffmpeg just went away
+			if out == streamPlayer.out && (playStatus == Playing || playStatus == Loading) {
+				// Not all ffmpeg builds and systems can do hardware decoding,
+				// if it died before playing anything try again without it
+				if !outputParser.Started && streamPlayer.UsingHWAccel() {
+					log.Println("ffmpeg failed with hardware accelerated decoding, falling back to software decoding")
+					streamPlayer.DisableHWAccel()
+					reloadStream()
+					continue
+				}
+				reconnect()
+				continue
+			}
+			time.Sleep(200 * time.Millisecond)
 		}
 	}()
 
+	// After the system sleeps the connection is long gone and the audio
+	// device may be in a bad way, start over instead of playing silence
+	go watchForResume(func() bool { return appRunning }, func(asleep time.Duration) {
+		log.Printf("The system was asleep for %s", asleep.Round(time.Second))
+		if playStatus != Playing {
+			return
+		}
+		playStatus = Loading
+		playButton.SetText("(Reconnecting)")
+		publishStatus()
+		streamPlayer.KillDecoder()
+	})
+
 	rsUrl, err := url.Parse("https://radiospiral.net")
 
 	if err != nil {
@@ -342,47 +1476,255 @@ func main() {
 
 	controlContainer := container.NewBorder(
 		nil,
-		nil,
+		container.NewCenter(skipToLiveButton),
 		volumeMute,
-		volumeTop,
+		container.NewHBox(pauseButton, reloadButton, volumeTop),
 		playButton,
 	)
 
-	// Layout the whole thing
-	window.SetContent(container.NewVBox(
-		radioSpiralHeaderImage,
-		container.NewCenter(widget.NewHyperlink("https://radiospiral.net", rsUrl)),
+	// Built below, once everything that goes in there is
+	var windowLayout *playerLayout
+	var windowContent *fyne.Container
+
+	settingsButton := NewTooltipButton(tooltips, theme.SettingsIcon(), "Settings", func() {
+		previousStreamURL := streamURL()
+		showSettingsDialog(window, prefs, currentStation, func() {
+			if streamURL() != previousStreamURL && playStatus == Playing {
+				reloadStream()
+			}
+			radioSpiralAvatar = loadPlaceholder(prefs.String(PREF_PLACEHOLDER))
+			metadata = newMetadataSource(currentStation)
+			setAlwaysOnTop(window, prefs.Bool(PREF_ALWAYS_ON_TOP))
+			artCache.SetDeadline(artDeadlinePreference(prefs))
+			artCache.SetLowData(prefs.Bool(PREF_LOW_DATA))
+			streamPlayer.SetVolumeOffset(stationVolumeOffset(prefs, currentStation))
+			windowLayout.SetMode(prefs.StringWithFallback(PREF_LAYOUT, LayoutAuto))
+			setClockStyle(prefs.StringWithFallback(PREF_CLOCK, ClockAuto))
+			go updateSchedule()
+			updateMediaKeys()
+			app.Settings().SetTheme(newScaledTheme(intPreference(prefs, PREF_UI_SCALE, UI_SCALE_RANGE)))
+			windowContent.Refresh()
+			silenceDetector.Configure(intPreference(prefs, PREF_SILENCE_THRESHOLD, SILENCE_THRESHOLD_RANGE), silenceDurationPreference(prefs))
+			silenceAlert.Store(prefs.Bool(PREF_SILENCE_ALERT))
+			if !silenceAlert.Load() {
+				silenceDetector.Reset()
+			}
+			streamPlayer.HWAccel = prefs.Bool(PREF_HWACCEL)
+			if prefs.Bool(PREF_SHOW_RAW_TITLE) {
+				rawTitleLabel.Show()
+			} else {
+				rawTitleLabel.Hide()
+			}
+			stationPoller.SetInterval(pollInterval())
+			keepAwake(playStatus == Playing)
+			refreshCard()
+		})
+	})
+
+	// For shared screens or just less distraction, the audio keeps going
+	var hideTrackButton *TooltipButton
+	updateHideTrackButton := func() {
+		if hideTrackInfo {
+			hideTrackButton.SetIcon(theme.VisibilityOffIcon())
+			hideTrackButton.SetTooltip("Show track info")
+		} else {
+			hideTrackButton.SetIcon(theme.VisibilityIcon())
+			hideTrackButton.SetTooltip("Hide track info")
+		}
+	}
+	hideTrackButton = NewTooltipButton(tooltips, theme.VisibilityIcon(), "Hide track info", func() {
+		hideTrackInfo = !hideTrackInfo
+		prefs.SetBool(PREF_HIDE_TRACK_INFO, hideTrackInfo)
+		updateHideTrackButton()
+		refreshCard()
+	})
+	updateHideTrackButton()
+
+	// ffmpeg downloads the stream on its own, we count it a second at a time
+	go func() {
+		for appRunning {
+			time.Sleep(time.Second)
+			if playStatus == Playing {
+				networkUsage.AddStreamSecond(outputParser.InputBitrate)
+			}
+		}
+	}()
+	historyButton := NewTooltipButton(tooltips, theme.HistoryIcon(), "Recently played", func() {
+		showHistoryDialog(window, songHistory)
+	})
+	statsButton := NewTooltipButton(tooltips, theme.InfoIcon(), "Session stats", func() {
+		format := playerState.Format()
+		if format == "" {
+			format = "unknown"
+		}
+		dialog.ShowInformation("Session stats", "Stream quality: "+format+"\n\n"+networkUsage.Summary(outputParser.InputBitrate), window)
+	})
+
+	var diagnosticsButton *TooltipButton
+	diagnosticsButton = NewTooltipButton(tooltips, theme.ContentCopyIcon(), "Copy diagnostics", func() {
+		// Checking the stream can take a while, don't hold the window
+		diagnosticsButton.Disable()
+		status := statusName(playStatus)
+		stream := streamURL()
+		song := currentSong
+		go func() {
+			report := buildDiagnostics(streamPlayer.player_name, status, stream, song, outputParser.History)
+			queueUIUpdate(func() {
+				window.Clipboard().SetContent(report)
+				dialog.ShowInformation("Diagnostics", "Diagnostics copied to the clipboard,\npaste them in your bug report.", window)
+				diagnosticsButton.Enable()
+			})
+		}()
+	})
+
+	// Layout the whole thing, the card moves to the side on wide windows
+	windowLayout = newPlayerLayout(centerCardContainer, prefs.StringWithFallback(PREF_LAYOUT, LayoutAuto))
+	windowContent = container.New(windowLayout,
+		announcementBanner,
+		radioSpiralHeader,
+		container.NewBorder(
+			nil,
+			nil,
+			listenersLabel,
+			container.NewHBox(dismissTitleButton, saveArtButton, hideTrackButton, historyButton, statsButton, diagnosticsButton, settingsButton),
+			container.NewCenter(widget.NewHyperlink("https://radiospiral.net", rsUrl)),
+		),
 		container.NewPadded(stationSelect),
+		deadAirLabel,
 		centerCardContainer,
+		scheduleLabel,
 		volumeContainer,
 		controlContainer,
-	))
+		bufferBar,
+	)
+	window.SetContent(tooltips.Wrap(container.NewStack(windowContent, container.NewCenter(volumeOSD), container.NewBorder(toasts, nil, nil, nil))))
 
-	// This small go routine will scroll the song title on the card if it is longer than MAX_CHARS
+	// Keep an eye on the player buffer, and if it keeps draining make it bigger
 	go func() {
-		for {
-			if !appRunning {
-				break
-			}
-			time.Sleep(1 * time.Second)
-			if len(currentSong) > MAX_CHARS {
-				topIndex := len(currentSong) - MAX_CHARS
-				currentSongScrollIndex += 1
-				if currentSongScrollIndex > topIndex {
-					currentSongScrollIndex = 0
+		lowReadings := 0
+		for appRunning {
+			time.Sleep(500 * time.Millisecond)
+			health := streamPlayer.BufferHealth()
+			if playStatus == Playing && health < LOW_BUFFER_THRESHOLD {
+				lowReadings += 1
+			} else {
+				lowReadings = 0
+			}
+			if lowReadings >= LOW_BUFFER_READINGS {
+				if streamPlayer.WidenBuffer() {
+					log.Printf("Buffer running low, widened it to %d bytes", streamPlayer.bufferSize)
 				}
-				scrolledTitle := currentSong[currentSongScrollIndex : currentSongScrollIndex+MAX_CHARS]
-				albumCard.SetSubTitle(scrolledTitle)
+				lowReadings = 0
+			}
+			bufferLow = lowReadings > 0
+			bufferBar.SetValue(health)
+		}
+	}()
+
+	// Go through the idle slideshow while the art stays the same for too long
+	go func() {
+		next := 0
+		for appRunning {
+			time.Sleep(IDLE_ART_INTERVAL)
+			dir := prefs.String(PREF_IDLE_ART_DIR)
+			if dir == "" || playStatus != Playing || time.Since(lastArtChange) < IDLE_ART_AFTER {
+				continue
+			}
+
+			files := imageFiles(dir)
+			if len(files) == 0 {
+				continue
+			}
+			next %= len(files)
+			img, err := loadImageFile(files[next])
+			next += 1
+			if err != nil {
+				log.Printf("Couldn't load idle art %s: %s", files[next-1], err)
+				continue
+			}
+			idleArt = img
+			refreshCard()
+		}
+	}()
+
+	// Nobody has touched the player for hours, most likely it was forgotten
+	// playing to an empty room
+	go func() {
+		for appRunning {
+			time.Sleep(AUTO_STOP_CHECK_INTERVAL)
+			if !prefs.Bool(PREF_AUTO_STOP) || playStatus != Playing {
+				continue
+			}
+			hours := intPreference(prefs, PREF_AUTO_STOP_HOURS, AUTO_STOP_HOURS_RANGE)
+			if time.Since(lastInteraction) < time.Duration(hours)*time.Hour {
+				continue
+			}
+
+			log.Printf("No activity for %d hours, stopping", hours)
+			handleMediaCommand(MediaStop)
+			app.SendNotification(fyne.NewNotification("Playback stopped", fmt.Sprintf("Nobody touched the player for %d hours, press play to keep listening", hours)))
+		}
+	}()
+
+	// Clean all stuff, only once, as we can get here both from closing the
+	// window and from a signal
+	var shutdownOnce sync.Once
+	shutdown := func() {
+		shutdownOnce.Do(func() {
+			if controlServer != nil {
+				controlServer.Close()
+			}
+			stationPoller.Stop()
+			streamPlayer.Close()
+			if teeSink != nil {
+				teeSink.Finish(SINK_CLOSE_TIMEOUT)
+			}
+			if trackLog != nil {
+				trackLog.Close()
+			}
+			inhibitor.Release()
+			appRunning = false
+			if logFile != nil {
+				defer logFile.Close()
 			}
+		})
+	}
+
+	window.SetOnClosed(shutdown)
+
+	// Closing while writing the audio with -tee would cut the recording, make
+	// sure that's what the user wants. Closing from here skips the intercept.
+	window.SetCloseIntercept(func() {
+		if teeSink == nil || !teeSink.Recording() {
+			window.Close()
+			return
 		}
+		dialog.ShowConfirm("Recording in progress", "Recording in progress — stop and quit?", func(quit bool) {
+			if quit {
+				window.Close()
+			}
+		}, window)
+	})
+
+	// If we get killed (system shutdown, kill, Ctrl+C on the terminal) make
+	// sure we don't leave an orphaned ffmpeg behind
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s, shutting down", sig)
+		shutdown()
+		app.Quit()
 	}()
 
-	// If the window is closed, clean all stuff
-	window.SetOnClosed(func() {
-		streamPlayer.Close()
-		appRunning = false
-		if logFile != nil {
-			defer logFile.Close()
+	app.Lifecycle().SetOnEnteredForeground(userActive)
+	app.Lifecycle().SetOnStarted(func() {
+		if prefs.Bool(PREF_ALWAYS_ON_TOP) {
+			setAlwaysOnTop(window, true)
+		}
+		showWelcomeDialog(window, prefs, streamPlayer.player_name)
+		if len(stations) > 0 {
+			startAutoplay()
 		}
 	})
 
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Keeps count of what the GUI asked the player to do
+type fakePlayer struct {
+	playing bool
+	stops   int
+}
+
+func (player *fakePlayer) Load(stream_url string, volume float64) error { return nil }
+func (player *fakePlayer) IsPlaying() bool                              { return player.playing }
+func (player *fakePlayer) IsMuted() bool                                { return false }
+func (player *fakePlayer) Play()                                        {}
+func (player *fakePlayer) Pause()                                       {}
+func (player *fakePlayer) Resume()                                      {}
+func (player *fakePlayer) IsPaused() bool                               { return false }
+func (player *fakePlayer) Mute()                                        {}
+func (player *fakePlayer) Stop()                                        { player.stops++ }
+func (player *fakePlayer) IncVolume()                                   {}
+func (player *fakePlayer) DecVolume()                                   {}
+func (player *fakePlayer) Close()                                       {}
+
+func TestTogglePlayback(t *testing.T) {
+	startErr := errors.New("no ffmpeg")
+
+	tests := []struct {
+		name string
+		// What the player and the GUI think is going on
+		status  int
+		playing bool
+		// What start returns, if it gets called
+		startErr error
+		// What we expect after the press
+		wantStatus int
+		wantStops  int
+		wantStarts int
+		wantErr    error
+	}{
+		{name: "stopped starts", status: Stopped, wantStatus: Loading, wantStarts: 1},
+		{name: "stopped failing to start stays stopped", status: Stopped, startErr: startErr, wantStatus: Stopped, wantStarts: 1, wantErr: startErr},
+		{name: "playing stops", status: Playing, playing: true, wantStatus: Stopped, wantStops: 1},
+		{name: "paused stops", status: Paused, wantStatus: Stopped, wantStops: 1},
+		{name: "buffering stops", status: Loading, playing: true, wantStatus: Stopped, wantStops: 1},
+		{name: "reconnecting stops", status: Loading, playing: false, wantStatus: Stopped, wantStops: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			player := &fakePlayer{playing: test.playing}
+			status := test.status
+			starts := 0
+			err := togglePlayback(player, &status, func() error {
+				starts++
+				return test.startErr
+			})
+
+			if err != test.wantErr {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+			if status != test.wantStatus {
+				t.Errorf("got status %s, want %s", statusName(status), statusName(test.wantStatus))
+			}
+			if player.stops != test.wantStops {
+				t.Errorf("stopped %d times, want %d", player.stops, test.wantStops)
+			}
+			if starts != test.wantStarts {
+				t.Errorf("started %d times, want %d", starts, test.wantStarts)
+			}
+		})
+	}
+}
+
+func TestTogglePlaybackMashed(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		playing    bool
+		wantStatus int
+		wantStops  int
+		wantStarts int
+	}{
+		{name: "stopped starts once", status: Stopped, wantStatus: Loading, wantStarts: 1},
+		{name: "playing stops once", status: Playing, playing: true, wantStatus: Stopped, wantStops: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			player := &fakePlayer{playing: test.playing}
+			status := test.status
+			starts := 0
+			press := func() {
+				togglePlayback(player, &status, func() error {
+					starts++
+					return nil
+				})
+			}
+
+			var gate pressGate
+			rested := make(chan struct{}, 1)
+			var presses sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				presses.Add(1)
+				go func() {
+					defer presses.Done()
+					gate.run(50*time.Millisecond, press, func() { rested <- struct{}{} })
+				}()
+			}
+			presses.Wait()
+
+			if status != test.wantStatus {
+				t.Errorf("got status %s, want %s", statusName(status), statusName(test.wantStatus))
+			}
+			if player.stops != test.wantStops || starts != test.wantStarts {
+				t.Errorf("stopped %d and started %d times, want %d and %d", player.stops, starts,
+					test.wantStops, test.wantStarts)
+			}
+
+			// Once rested the button works again
+			select {
+			case <-rested:
+			case <-time.After(time.Second):
+				t.Fatal("the button never rested")
+			}
+			if !gate.run(50*time.Millisecond, press, nil) {
+				t.Error("a press after resting was ignored")
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * A single line of text that scrolls back and forth when it doesn't fit, used
+ * for the track title on the card. It's measured with the real font, so it only
+ * scrolls when it really overflows, and moves by pixels instead of characters.
+ */
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How fast the text moves, in pixels per second
+const MARQUEE_SPEED = 30
+
+// How long the text stays still at each end
+const MARQUEE_PAUSE = 2 * time.Second
+
+type Marquee struct {
+	widget.BaseWidget
+	text *canvas.Text
+	// How far the text is scrolled to the left
+	offset float32
+	// The animation running and the overflow it was made for
+	animation *fyne.Animation
+	overflow  float32
+	// The mouse is over it, so it stays still to be read
+	hovered bool
+}
+
+func NewMarquee(text string) *Marquee {
+	marquee := &Marquee{text: canvas.NewText(text, theme.ForegroundColor())}
+	marquee.ExtendBaseWidget(marquee)
+	return marquee
+}
+
+func (marquee *Marquee) SetText(text string) {
+	if text == marquee.text.Text {
+		return
+	}
+	marquee.text.Text = text
+	marquee.stopAnimation()
+	marquee.Refresh()
+}
+
+func (marquee *Marquee) stopAnimation() {
+	if marquee.animation != nil {
+		marquee.animation.Stop()
+		marquee.animation = nil
+	}
+	marquee.overflow = 0
+	marquee.offset = 0
+}
+
+// Starts or stops scrolling depending on how much the text overflows the width
+func (marquee *Marquee) updateAnimation(width float32) {
+	overflow := marquee.text.MinSize().Width - width
+	if overflow <= 0 {
+		marquee.stopAnimation()
+		return
+	}
+	if overflow == marquee.overflow || marquee.hovered {
+		return
+	}
+
+	marquee.stopAnimation()
+	marquee.overflow = overflow
+
+	// Each way takes the pauses at both ends plus the travel, the curve keeps
+	// the text still during the pauses
+	travel := time.Duration(float64(overflow) / MARQUEE_SPEED * float64(time.Second))
+	duration := travel + 2*MARQUEE_PAUSE
+	pause := float32(MARQUEE_PAUSE) / float32(duration)
+	marquee.animation = fyne.NewAnimation(duration, func(progress float32) {
+		marquee.offset = progress * overflow
+		marquee.text.Move(fyne.NewPos(-marquee.offset, 0))
+	})
+	marquee.animation.Curve = func(progress float32) float32 {
+		return fyne.Min(fyne.Max((progress-pause)/(1-2*pause), 0), 1)
+	}
+	marquee.animation.AutoReverse = true
+	marquee.animation.RepeatCount = fyne.AnimationRepeatForever
+	marquee.animation.Start()
+}
+
+// Stops scrolling where it is while the mouse is over it
+func (marquee *Marquee) MouseIn(event *desktop.MouseEvent) {
+	marquee.hovered = true
+	if marquee.animation != nil {
+		marquee.animation.Stop()
+		marquee.animation = nil
+	}
+}
+
+func (marquee *Marquee) MouseMoved(event *desktop.MouseEvent) {}
+
+// Scrolls again from the start
+func (marquee *Marquee) MouseOut() {
+	marquee.hovered = false
+	marquee.stopAnimation()
+	marquee.Refresh()
+}
+
+func (marquee *Marquee) CreateRenderer() fyne.WidgetRenderer {
+	content := container.New(&marqueeLayout{marquee: marquee}, marquee.text)
+	// A scroll that doesn't scroll, just to clip the text to our bounds
+	clip := container.NewScroll(content)
+	clip.Direction = container.ScrollNone
+	return &marqueeRenderer{marquee: marquee, clip: clip}
+}
+
+// Lays out the text where the marquee has scrolled it, or centered if it fits
+type marqueeLayout struct {
+	marquee *Marquee
+}
+
+func (layout *marqueeLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	text := layout.marquee.text
+	text.Resize(text.MinSize())
+	if text.MinSize().Width <= size.Width {
+		text.Move(fyne.NewPos((size.Width-text.MinSize().Width)/2, 0))
+	} else {
+		text.Move(fyne.NewPos(-layout.marquee.offset, 0))
+	}
+}
+
+// Any width will do, that's the point
+func (layout *marqueeLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	return fyne.NewSize(0, layout.marquee.text.MinSize().Height)
+}
+
+type marqueeRenderer struct {
+	marquee *Marquee
+	clip    *container.Scroll
+}
+
+func (renderer *marqueeRenderer) Layout(size fyne.Size) {
+	renderer.clip.Resize(size)
+	renderer.marquee.updateAnimation(size.Width)
+	renderer.clip.Content.Refresh()
+}
+
+func (renderer *marqueeRenderer) MinSize() fyne.Size {
+	return renderer.clip.MinSize()
+}
+
+func (renderer *marqueeRenderer) Refresh() {
+	renderer.marquee.text.Color = theme.ForegroundColor()
+	renderer.marquee.text.TextSize = theme.TextSize()
+	renderer.Layout(renderer.marquee.Size())
+	canvas.Refresh(renderer.marquee.text)
+}
+
+func (renderer *marqueeRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{renderer.clip}
+}
+
+func (renderer *marqueeRenderer) Destroy() {
+	renderer.marquee.stopAnimation()
+}
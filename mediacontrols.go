@@ -0,0 +1,70 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Integration with the media controls of the desktop: the now playing info the
+ * OS shows and the media keys. Each platform has its own implementation, the
+ * GUI only talks to the mediaControls interface.
+ */
+
+import (
+	"image"
+	"strings"
+)
+
+// What the OS asks us to do, the values are shared with the native code
+type MediaCommand int
+
+const (
+	MediaPlay MediaCommand = iota
+	MediaPause
+	MediaTogglePlayPause
+	MediaStop
+	// Only from the media keys, the OS controls take care of the volume
+	MediaVolumeUp
+	MediaVolumeDown
+	MediaMute
+)
+
+type mediaControls interface {
+	// Tells the OS what we are playing, art can be nil
+	SetNowPlaying(title string, artist string, art image.Image)
+	SetPlaying(playing bool)
+	// Removes us from the OS now playing info
+	Clear()
+	// Where the commands coming from the OS go
+	OnCommand(handler func(command MediaCommand))
+}
+
+// For platforms we don't integrate with
+type noMediaControls struct{}
+
+func (noMediaControls) SetNowPlaying(title string, artist string, art image.Image) {}
+func (noMediaControls) SetPlaying(playing bool)                                    {}
+func (noMediaControls) Clear()                                                     {}
+func (noMediaControls) OnCommand(handler func(command MediaCommand))               {}
+
+// Stream titles usually come as "Artist - Title"
+func splitStreamTitle(streamTitle string) (artist string, title string) {
+	artist, title, found := strings.Cut(streamTitle, " - ")
+	if !found {
+		return "", strings.TrimSpace(streamTitle)
+	}
+	return strings.TrimSpace(artist), strings.TrimSpace(title)
+}
@@ -0,0 +1,109 @@
+//go:build darwin
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * macOS Now Playing (Control Center) and media keys, through the MediaPlayer
+ * framework. The Objective-C side lives in mediacontrols_darwin.m.
+ */
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework Foundation -framework AppKit -framework MediaPlayer
+#include <stdlib.h>
+
+void mediaControlsStart(void);
+void mediaControlsSetNowPlaying(const char *title, const char *artist, const void *art, int artLength);
+void mediaControlsSetPlaying(int playing);
+void mediaControlsClear(void);
+*/
+import "C"
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"log"
+	"sync"
+	"unsafe"
+)
+
+// The native callbacks can't carry Go state, so the handler lives here
+var mediaCommandMutex sync.Mutex
+var mediaCommandHandler func(command MediaCommand)
+
+//export goMediaCommand
+func goMediaCommand(command C.int) {
+	mediaCommandMutex.Lock()
+	handler := mediaCommandHandler
+	mediaCommandMutex.Unlock()
+
+	if handler != nil {
+		handler(MediaCommand(command))
+	}
+}
+
+type darwinMediaControls struct{}
+
+func newMediaControls() mediaControls {
+	C.mediaControlsStart()
+	return darwinMediaControls{}
+}
+
+func (darwinMediaControls) SetNowPlaying(title string, artist string, art image.Image) {
+	cTitle := C.CString(title)
+	defer C.free(unsafe.Pointer(cTitle))
+	cArtist := C.CString(artist)
+	defer C.free(unsafe.Pointer(cArtist))
+
+	var encoded bytes.Buffer
+	if art != nil {
+		err := png.Encode(&encoded, art)
+		if err != nil {
+			log.Printf("Couldn't pass the art to Now Playing: %s", err)
+			encoded.Reset()
+		}
+	}
+
+	var cArt unsafe.Pointer
+	if encoded.Len() > 0 {
+		cArt = C.CBytes(encoded.Bytes())
+		defer C.free(cArt)
+	}
+	C.mediaControlsSetNowPlaying(cTitle, cArtist, cArt, C.int(encoded.Len()))
+}
+
+func (darwinMediaControls) SetPlaying(playing bool) {
+	if playing {
+		C.mediaControlsSetPlaying(1)
+	} else {
+		C.mediaControlsSetPlaying(0)
+	}
+}
+
+func (darwinMediaControls) Clear() {
+	C.mediaControlsClear()
+}
+
+func (darwinMediaControls) OnCommand(handler func(command MediaCommand)) {
+	mediaCommandMutex.Lock()
+	defer mediaCommandMutex.Unlock()
+	mediaCommandHandler = handler
+}
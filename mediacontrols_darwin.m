@@ -0,0 +1,82 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+// Native side of mediacontrols_darwin.go
+
+#import <Foundation/Foundation.h>
+#import <AppKit/AppKit.h>
+#import <MediaPlayer/MediaPlayer.h>
+#include "_cgo_export.h"
+
+// Same values as MediaCommand in mediacontrols.go
+enum {
+	MEDIA_PLAY = 0,
+	MEDIA_PAUSE = 1,
+	MEDIA_TOGGLE_PLAY_PAUSE = 2,
+	MEDIA_STOP = 3,
+};
+
+static void addCommand(MPRemoteCommand *remoteCommand, int command) {
+	remoteCommand.enabled = YES;
+	[remoteCommand addTargetWithHandler:^MPRemoteCommandHandlerStatus(MPRemoteCommandEvent *event) {
+		goMediaCommand(command);
+		return MPRemoteCommandHandlerStatusSuccess;
+	}];
+}
+
+void mediaControlsStart(void) {
+	MPRemoteCommandCenter *center = [MPRemoteCommandCenter sharedCommandCenter];
+	addCommand(center.playCommand, MEDIA_PLAY);
+	addCommand(center.pauseCommand, MEDIA_PAUSE);
+	addCommand(center.togglePlayPauseCommand, MEDIA_TOGGLE_PLAY_PAUSE);
+	addCommand(center.stopCommand, MEDIA_STOP);
+
+	// It's a radio, there's nothing to skip or seek to
+	center.nextTrackCommand.enabled = NO;
+	center.previousTrackCommand.enabled = NO;
+	center.changePlaybackPositionCommand.enabled = NO;
+}
+
+void mediaControlsSetNowPlaying(const char *title, const char *artist, const void *art, int artLength) {
+	NSMutableDictionary *info = [NSMutableDictionary dictionary];
+	info[MPMediaItemPropertyTitle] = [NSString stringWithUTF8String:title];
+	info[MPMediaItemPropertyArtist] = [NSString stringWithUTF8String:artist];
+	info[MPNowPlayingInfoPropertyIsLiveStream] = @YES;
+
+	if (art != NULL && artLength > 0) {
+		NSImage *image = [[NSImage alloc] initWithData:[NSData dataWithBytes:art length:artLength]];
+		if (image != nil) {
+			info[MPMediaItemPropertyArtwork] = [[MPMediaItemArtwork alloc]
+				initWithBoundsSize:image.size
+				requestHandler:^NSImage *(CGSize size) {
+					return image;
+				}];
+		}
+	}
+
+	[MPNowPlayingInfoCenter defaultCenter].nowPlayingInfo = info;
+}
+
+void mediaControlsSetPlaying(int playing) {
+	[MPNowPlayingInfoCenter defaultCenter].playbackState =
+		playing ? MPNowPlayingPlaybackStatePlaying : MPNowPlayingPlaybackStatePaused;
+}
+
+void mediaControlsClear(void) {
+	[MPNowPlayingInfoCenter defaultCenter].nowPlayingInfo = nil;
+	[MPNowPlayingInfoCenter defaultCenter].playbackState = MPNowPlayingPlaybackStateStopped;
+}
@@ -0,0 +1,165 @@
+//go:build linux
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Media keys straight from the input devices, for minimal setups (a tiling
+ * window manager, no desktop) where nothing turns them into MPRIS commands. We
+ * only listen, the keys still reach everything else. Reading /dev/input needs
+ * the user in the input group, without it there are no keys and that's it.
+ */
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// From linux/input-event-codes.h
+const EV_KEY = 0x01
+
+const (
+	KEY_MUTE       = 113
+	KEY_VOLUMEDOWN = 114
+	KEY_VOLUMEUP   = 115
+	KEY_PLAYPAUSE  = 164
+	KEY_STOPCD     = 166
+	KEY_PLAYCD     = 200
+	KEY_PAUSECD    = 201
+	KEY_PLAY       = 207
+)
+
+// Key values in the events
+const (
+	KEY_PRESSED   = 1
+	KEY_REPEATING = 2
+)
+
+var MEDIA_KEY_COMMANDS = map[uint16]MediaCommand{
+	KEY_MUTE:       MediaMute,
+	KEY_VOLUMEDOWN: MediaVolumeDown,
+	KEY_VOLUMEUP:   MediaVolumeUp,
+	KEY_PLAYPAUSE:  MediaTogglePlayPause,
+	KEY_PLAYCD:     MediaPlay,
+	KEY_PLAY:       MediaPlay,
+	KEY_PAUSECD:    MediaPause,
+	KEY_STOPCD:     MediaStop,
+}
+
+var errNoMediaKeyDevices = errors.New("no input device with media keys we can read, is the user in the input group?")
+
+// struct input_event
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+type MediaKeyListener struct {
+	mutex   sync.Mutex
+	devices []*os.File
+}
+
+// Starts reading the keys of every device that has media keys
+func (listener *MediaKeyListener) Start(handler func(command MediaCommand)) error {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	if listener.devices != nil {
+		return nil
+	}
+
+	paths, _ := filepath.Glob("/dev/input/event*")
+	for _, path := range paths {
+		if !hasMediaKeys(filepath.Base(path)) {
+			continue
+		}
+		device, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		listener.devices = append(listener.devices, device)
+		go readMediaKeys(device, handler)
+	}
+	if listener.devices == nil {
+		return errNoMediaKeyDevices
+	}
+	log.Printf("Listening to the media keys of %d input devices", len(listener.devices))
+	return nil
+}
+
+func (listener *MediaKeyListener) Stop() {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+
+	for _, device := range listener.devices {
+		device.Close()
+	}
+	listener.devices = nil
+}
+
+// Until the device is closed or goes away
+func readMediaKeys(device *os.File, handler func(command MediaCommand)) {
+	for {
+		var event inputEvent
+		err := binary.Read(device, binary.NativeEndian, &event)
+		if err != nil {
+			return
+		}
+		if event.Type != EV_KEY {
+			continue
+		}
+		command, found := MEDIA_KEY_COMMANDS[event.Code]
+		if !found {
+			continue
+		}
+		// Holding the volume keys keeps changing it, the rest only count once
+		repeats := command == MediaVolumeUp || command == MediaVolumeDown
+		if event.Value == KEY_PRESSED || (repeats && event.Value == KEY_REPEATING) {
+			handler(command)
+		}
+	}
+}
+
+// The kernel tells which keys a device has as a bitmap, in hex words with the
+// highest first. Keyboards without media keys and mice are left out.
+func hasMediaKeys(eventName string) bool {
+	capabilities, err := os.ReadFile(filepath.Join("/sys/class/input", eventName, "device/capabilities/key"))
+	if err != nil {
+		return false
+	}
+	words := strings.Fields(string(capabilities))
+	keys := new(big.Int)
+	for _, word := range words {
+		value, ok := new(big.Int).SetString(word, 16)
+		if !ok {
+			return false
+		}
+		keys.Lsh(keys, strconv.IntSize).Or(keys, value)
+	}
+	return keys.Bit(KEY_PLAYPAUSE) == 1 || keys.Bit(KEY_VOLUMEUP) == 1
+}
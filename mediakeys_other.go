@@ -0,0 +1,32 @@
+//go:build !linux
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import "errors"
+
+// Only Linux reads the keys from the input devices, elsewhere the OS media
+// controls take care of them
+type MediaKeyListener struct{}
+
+func (listener *MediaKeyListener) Start(handler func(command MediaCommand)) error {
+	return errors.New("reading the media keys is only supported on Linux")
+}
+
+func (listener *MediaKeyListener) Stop() {}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Where the now playing, schedule and announcement information comes from. The
+ * GUI only talks to a MetadataSource, so we can feed it canned JSON files instead
+ * of the live station API when working on the display.
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+type MetadataSource interface {
+	NowPlaying() (*StationResponse, error)
+	Schedule() ([]BroadcastResponse, error)
+	// Nil if the station has nothing to announce
+	Announcement() (*Announcement, error)
+}
+
+// Metadata from the AzuraCast API of the station
+type HTTPMetadataSource struct {
+	station StationInfo
+	// Sent along the requests if not empty
+	apiKey string
+	// Where the station publishes its announcements, if it does
+	announcementURL string
+}
+
+func NewHTTPMetadataSource(station StationInfo, apiKey string, announcementURL string) *HTTPMetadataSource {
+	return &HTTPMetadataSource{station: station, apiKey: apiKey, announcementURL: announcementURL}
+}
+
+func (source *HTTPMetadataSource) NowPlaying() (*StationResponse, error) {
+	return queryStation(source.station, source.apiKey)
+}
+
+func (source *HTTPMetadataSource) Schedule() ([]BroadcastResponse, error) {
+	return querySchedule(source.station, source.apiKey)
+}
+
+func (source *HTTPMetadataSource) Announcement() (*Announcement, error) {
+	if source.announcementURL == "" {
+		return nil, nil
+	}
+	return queryAnnouncement(source.announcementURL, source.apiKey)
+}
+
+// Metadata from nowplaying.json, schedule.json and, if there's one,
+// announcement.json files in a directory
+type FileMetadataSource struct {
+	dir string
+}
+
+func NewFileMetadataSource(dir string) *FileMetadataSource {
+	return &FileMetadataSource{dir: dir}
+}
+
+func (source *FileMetadataSource) NowPlaying() (*StationResponse, error) {
+	body, err := os.ReadFile(filepath.Join(source.dir, "nowplaying.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeStationResponse(body, "")
+}
+
+func (source *FileMetadataSource) Schedule() ([]BroadcastResponse, error) {
+	body, err := os.ReadFile(filepath.Join(source.dir, "schedule.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var response []BroadcastResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (source *FileMetadataSource) Announcement() (*Announcement, error) {
+	body, err := os.ReadFile(filepath.Join(source.dir, "announcement.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeAnnouncement(body)
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Keeping the window above the others. Fyne doesn't do this, so we reach for the
+ * native window on the platforms where we know how to.
+ */
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver"
+)
+
+// Puts the window above the others, or back to normal. The window has to be
+// shown already for this to work.
+func setAlwaysOnTop(window fyne.Window, onTop bool) {
+	nativeWindow, ok := window.(driver.NativeWindow)
+	if !ok {
+		log.Println("Can't keep the window on top here")
+		return
+	}
+
+	nativeWindow.RunNative(func(context any) {
+		if !setNativeOnTop(context, onTop) {
+			log.Println("Can't keep the window on top on this platform")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+//go:build darwin
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#import <AppKit/AppKit.h>
+
+static void setWindowOnTop(uintptr_t window, int onTop) {
+	NSWindow *nsWindow = (__bridge NSWindow *)(void *)window;
+	[nsWindow setLevel:onTop ? NSFloatingWindowLevel : NSNormalWindowLevel];
+}
+*/
+import "C"
+
+import "fyne.io/fyne/v2/driver"
+
+func setNativeOnTop(context any, onTop bool) bool {
+	windowContext, ok := context.(driver.MacWindowContext)
+	if !ok || windowContext.NSWindow == 0 {
+		return false
+	}
+
+	if onTop {
+		C.setWindowOnTop(C.uintptr_t(windowContext.NSWindow), 1)
+	} else {
+		C.setWindowOnTop(C.uintptr_t(windowContext.NSWindow), 0)
+	}
+	return true
+}
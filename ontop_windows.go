@@ -0,0 +1,47 @@
+//go:build windows
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"syscall"
+
+	"fyne.io/fyne/v2/driver"
+)
+
+const HWND_TOPMOST = ^uintptr(0)
+const HWND_NOTOPMOST = ^uintptr(1)
+const SWP_NOSIZE = 0x0001
+const SWP_NOMOVE = 0x0002
+
+var procSetWindowPos = syscall.NewLazyDLL("user32.dll").NewProc("SetWindowPos")
+
+func setNativeOnTop(context any, onTop bool) bool {
+	windowContext, ok := context.(driver.WindowsWindowContext)
+	if !ok || windowContext.HWND == 0 {
+		return false
+	}
+
+	insertAfter := HWND_NOTOPMOST
+	if onTop {
+		insertAfter = HWND_TOPMOST
+	}
+	result, _, _ := procSetWindowPos.Call(windowContext.HWND, insertAfter, 0, 0, 0, 0, SWP_NOMOVE|SWP_NOSIZE)
+	return result != 0
+}
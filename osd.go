@@ -0,0 +1,91 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * A box with the volume that shows up over the window for a moment each time
+ * the user changes it, so there's no need to look for the volume bar.
+ */
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How long the volume stays on screen after the last change
+const OSD_DURATION = time.Second
+
+type VolumeOSD struct {
+	*fyne.Container
+	label *widget.Label
+	bar   *widget.ProgressBar
+	mutex sync.Mutex
+	// Hides the box, pushed back by each change
+	hideTimer *time.Timer
+}
+
+func NewVolumeOSD() *VolumeOSD {
+	background := canvas.NewRectangle(theme.OverlayBackgroundColor())
+	background.StrokeColor = theme.ShadowColor()
+	background.StrokeWidth = 1
+	background.CornerRadius = theme.InputRadiusSize()
+
+	label := widget.NewLabel("")
+	label.Alignment = fyne.TextAlignCenter
+	label.TextStyle = fyne.TextStyle{Bold: true}
+	bar := widget.NewProgressBar()
+	bar.TextFormatter = func() string { return "" }
+
+	osd := &VolumeOSD{
+		Container: container.NewStack(background, container.NewPadded(container.NewVBox(label, bar))),
+		label:     label,
+		bar:       bar,
+	}
+	osd.Hide()
+	return osd
+}
+
+// Shows the volume, and hides it again once it stops changing
+func (osd *VolumeOSD) Flash(volume float64, muted bool) {
+	queueUIUpdate(func() {
+		if muted {
+			osd.label.SetText("Muted")
+			osd.bar.SetValue(0)
+		} else {
+			osd.label.SetText(fmt.Sprintf("Volume %.0f%%", volume*100))
+			osd.bar.SetValue(volume)
+		}
+		osd.Show()
+	})
+
+	osd.mutex.Lock()
+	defer osd.mutex.Unlock()
+	if osd.hideTimer != nil {
+		osd.hideTimer.Stop()
+	}
+	osd.hideTimer = time.AfterFunc(OSD_DURATION, func() {
+		queueUIUpdate(osd.Hide)
+	})
+}
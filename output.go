@@ -0,0 +1,270 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * ffmpeg tells us what is going on with the stream through its stderr: when the
+ * audio starts flowing, when the stream title changes, errors... We parse it here
+ * and let whoever is interested know through callbacks.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// How many lines of ffmpeg output we keep around after they are parsed
+const OUTPUT_HISTORY_LINES = 200
+
+// Longest line of output we take in one piece, longer ones get cut
+const MAX_OUTPUT_LINE = 1024 * 1024
+
+// Kinds of trouble ffmpeg can report about the stream
+type StreamError int
+
+const (
+	NoStreamError StreamError = iota
+	// We were connected and the connection dropped
+	ConnectionLost
+	// We couldn't connect at all
+	ConnectionFailed
+	// The server doesn't have that stream
+	StreamNotFound
+	// The server doesn't let us in
+	AccessDenied
+)
+
+func (streamError StreamError) String() string {
+	switch streamError {
+	case ConnectionLost:
+		return "Connection lost"
+	case ConnectionFailed:
+		return "Couldn't connect to the stream"
+	case StreamNotFound:
+		return "The stream was not found"
+	case AccessDenied:
+		return "Access to the stream was denied"
+	}
+	return "No error"
+}
+
+// What ffmpeg (and the libraries it uses) say when things go wrong
+var STREAM_ERROR_SIGNATURES = []struct {
+	signature string
+	kind      StreamError
+}{
+	{"Connection reset by peer", ConnectionLost},
+	{"Broken pipe", ConnectionLost},
+	{"Stream ends prematurely", ConnectionLost},
+	{"Will reconnect at", ConnectionLost},
+	{"Connection timed out", ConnectionFailed},
+	{"Connection refused", ConnectionFailed},
+	{"Network is unreachable", ConnectionFailed},
+	{"Failed to resolve hostname", ConnectionFailed},
+	{"Name or service not known", ConnectionFailed},
+	{"404 Not Found", StreamNotFound},
+	{"401 Unauthorized", AccessDenied},
+	{"403 Forbidden", AccessDenied},
+}
+
+func classifyFFmpegError(line string) StreamError {
+	for _, elem := range STREAM_ERROR_SIGNATURES {
+		if strings.Contains(line, elem.signature) {
+			return elem.kind
+		}
+	}
+	return NoStreamError
+}
+
+// The rate of the stream when ffmpeg describes it, as in
+// "Stream #0:0: Audio: mp3, 44100 Hz, stereo, fltp, 128 kb/s"
+var STREAM_RATE_PATTERN = regexp.MustCompile(`Stream #\d+:\d+.*: Audio: .*?, (\d+) Hz`)
+
+// The codec of the stream, in the same line
+var STREAM_CODEC_PATTERN = regexp.MustCompile(`Stream #\d+:\d+.*: Audio: ([^\s,(]+)`)
+
+// The bitrate of the stream, in the same line, or in the summary of the input
+// as in "Duration: N/A, start: 0.000000, bitrate: 128 kb/s"
+var STREAM_BITRATE_PATTERN = regexp.MustCompile(`(?:Stream #\d+:\d+.*: Audio: .*|bitrate:) (\d+) kb/s`)
+
+// What ffmpeg says when the decoded audio changes format mid-stream, as in
+// "Input stream #0:0 frame changed from rate:44100 ... to rate:48000 ..."
+var RATE_CHANGE_PATTERN = regexp.MustCompile(`frame changed from rate:(\d+).* to rate:(\d+)`)
+
+type OutputParser struct {
+	// Last lines of output, logged or not, so we can look back after a failure
+	History *RingBuffer
+	// Prefix for the lines in the log, empty to not log them
+	LogPrefix string
+
+	// ffmpeg started sending audio
+	OnPlaying func()
+	// The stream title changed
+	OnTitle func(title string)
+	// The whole line the title came in, as the player wrote it
+	OnRawTitle func(line string)
+	// ffmpeg complained about the stream
+	OnError func(streamError StreamError, line string)
+
+	// Sample rate of the stream, 0 until ffmpeg tells us. We always ask
+	// ffmpeg for SAMPLE_RATE, so it resamples whatever comes.
+	InputSampleRate int
+	// Bitrate of the stream in kb/s, 0 until ffmpeg tells us. Not all
+	// streams have one.
+	InputBitrate int
+	// Codec of the stream as ffmpeg calls it, like mp3 or aac
+	InputCodec string
+	// Whether the stream lines we are reading describe the output
+	inOutput bool
+	// Whether the ffmpeg we are reading got to send audio
+	Started bool
+}
+
+func NewOutputParser() *OutputParser {
+	return &OutputParser{History: NewRingBuffer(OUTPUT_HISTORY_LINES)}
+}
+
+// Lines come whole, so multibyte characters are never split, but stations do
+// send broken titles: invalid sequences become replacement characters instead
+// of garbling the rest of the line.
+func (parser *OutputParser) ParseLine(line string) {
+	line = strings.ToValidUTF8(line, string(utf8.RuneError))
+	// ffmpeg prints the stream URL, which may have credentials in it. Only
+	// what we keep gets redacted, titles with URLs in them are the station's.
+	redacted := redactURLs(line)
+	parser.History.Add(redacted)
+	if parser.LogPrefix != "" {
+		log.Print(parser.LogPrefix + redacted)
+	}
+
+	if strings.Contains(line, "Input #0") {
+		parser.inOutput = false
+	}
+	if strings.Contains(line, "Output #0") {
+		parser.inOutput = true
+	}
+	// ffmpeg starts its output, or mpv opens the audio device
+	if strings.Contains(line, "Output #0") || strings.HasPrefix(strings.TrimSpace(line), "AO: [") {
+		parser.Started = true
+		if parser.OnPlaying != nil {
+			parser.OnPlaying()
+		}
+	}
+	parser.parseStreamFormat(line)
+
+	// ffmpeg and mpv have their own way of telling the title
+	for _, marker := range []string{"StreamTitle: ", "icy-title: "} {
+		if strings.Contains(line, marker) && parser.OnRawTitle != nil {
+			parser.OnRawTitle(line)
+		}
+		if strings.Contains(line, marker) && parser.OnTitle != nil {
+			newTitleParts := strings.Split(line, marker)
+			log.Printf("Found new stream title: %s", truncateTitle(redactURLs(newTitleParts[1]), MAX_LOG_TITLE))
+			parser.OnTitle(newTitleParts[1])
+		}
+	}
+
+	if streamError := classifyFFmpegError(line); streamError != NoStreamError && parser.OnError != nil {
+		parser.OnError(streamError, redacted)
+	}
+}
+
+// Keeps track of the input codec, sample rate and bitrate. Encoders restarting at a
+// different rate do happen, our audio device can't change its rate, but ffmpeg
+// resamples the new one to ours without us doing anything, so we just log it.
+func (parser *OutputParser) parseStreamFormat(line string) {
+	if match := RATE_CHANGE_PATTERN.FindStringSubmatch(line); match != nil {
+		rate, _ := strconv.Atoi(match[2])
+		log.Printf("Stream sample rate changed from %s Hz to %d Hz, resampling to %d Hz", match[1], rate, SAMPLE_RATE)
+		parser.InputSampleRate = rate
+		return
+	}
+
+	if parser.inOutput {
+		return
+	}
+	if match := STREAM_CODEC_PATTERN.FindStringSubmatch(line); match != nil {
+		parser.InputCodec = match[1]
+	}
+	if match := STREAM_BITRATE_PATTERN.FindStringSubmatch(line); match != nil {
+		parser.InputBitrate, _ = strconv.Atoi(match[1])
+	}
+	if match := STREAM_RATE_PATTERN.FindStringSubmatch(line); match != nil {
+		rate, _ := strconv.Atoi(match[1])
+		if parser.InputSampleRate != 0 && rate != parser.InputSampleRate {
+			log.Printf("Stream sample rate changed from %d Hz to %d Hz, resampling to %d Hz", parser.InputSampleRate, rate, SAMPLE_RATE)
+		}
+		parser.InputSampleRate = rate
+	}
+}
+
+// What we know about the stream quality, like "mp3, 128 kb/s, 44.1 kHz",
+// leaving out what ffmpeg didn't tell
+func (parser *OutputParser) StreamFormat() string {
+	parts := []string{}
+	if parser.InputCodec != "" {
+		parts = append(parts, parser.InputCodec)
+	}
+	if parser.InputBitrate > 0 {
+		parts = append(parts, fmt.Sprintf("%d kb/s", parser.InputBitrate))
+	}
+	if parser.InputSampleRate > 0 {
+		parts = append(parts, strconv.FormatFloat(float64(parser.InputSampleRate)/1000, 'f', -1, 64)+" kHz")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Parses the output until it ends
+func (parser *OutputParser) Process(out io.Reader) error {
+	parser.Started = false
+	// Not every stream tells its format, don't keep the one of the last
+	parser.InputBitrate = 0
+	parser.InputCodec = ""
+	parser.InputSampleRate = 0
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(make([]byte, 4096), MAX_OUTPUT_LINE)
+	scanner.Split(scanOutputLines)
+	for scanner.Scan() {
+		parser.ParseLine(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// Like bufio.ScanLines, but progress lines end in \r instead of \n, and a line
+// too long for the buffer comes in pieces instead of stopping the scanner
+func scanOutputLines(data []byte, atEOF bool) (int, []byte, error) {
+	if end := bytes.IndexAny(data, "\r\n"); end >= 0 {
+		if end == 0 {
+			// The \n of a \r\n, or an empty line
+			return 1, nil, nil
+		}
+		return end + 1, data[:end], nil
+	}
+	if len(data) > 0 && (atEOF || len(data) >= MAX_OUTPUT_LINE) {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		// What the parser should make of it
+		title     string
+		started   bool
+		codec     string
+		rate      int
+		bitrate   int
+		lastError StreamError
+	}{
+		{
+			name:  "ffmpeg title",
+			line:  "  StreamTitle: Steve Roach - Structures from Silence",
+			title: "Steve Roach - Structures from Silence",
+		},
+		{
+			name:  "mpv title",
+			line:  " icy-title: Robert Rich - Rainforest",
+			title: "Robert Rich - Rainforest",
+		},
+		{
+			// Redacting is for the log, the title is the station's
+			name:  "title with a URL",
+			line:  "  StreamTitle: Listen at https://example.com/live?ref=player",
+			title: "Listen at https://example.com/live?ref=player",
+		},
+		{
+			name:    "ffmpeg output",
+			line:    "Output #0, wav, to 'pipe:':",
+			started: true,
+		},
+		{
+			name:    "mpv audio",
+			line:    "AO: [pulse] 44100Hz stereo 2ch s16",
+			started: true,
+		},
+		{
+			name:    "stream format",
+			line:    "  Stream #0:0: Audio: mp3, 44100 Hz, stereo, fltp, 128 kb/s",
+			codec:   "mp3",
+			rate:    44100,
+			bitrate: 128,
+		},
+		{
+			name:      "connection lost",
+			line:      "[tls @ 0x55d0c8] Error in the pull function: Connection reset by peer",
+			lastError: ConnectionLost,
+		},
+		{
+			name:      "stream not found",
+			line:      "[https @ 0x55d0c8] HTTP error 404 Not Found",
+			lastError: StreamNotFound,
+		},
+		{
+			// The bytes of "é" with junk in between, all of it one replacement
+			name:  "invalid UTF-8 in the title",
+			line:  "  StreamTitle: Caf\xc3\xff\xfe\xa9 del Mar",
+			title: "Caf\uFFFD del Mar",
+		},
+		{
+			name: "nothing of interest",
+			line: "  Metadata:",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser := NewOutputParser()
+			title := ""
+			parser.OnTitle = func(newTitle string) {
+				title = newTitle
+			}
+			lastError := NoStreamError
+			parser.OnError = func(streamError StreamError, line string) {
+				lastError = streamError
+			}
+			parser.ParseLine(test.line)
+
+			if title != test.title {
+				t.Errorf("title %q, want %q", title, test.title)
+			}
+			if parser.Started != test.started {
+				t.Errorf("started %t, want %t", parser.Started, test.started)
+			}
+			if parser.InputCodec != test.codec || parser.InputSampleRate != test.rate || parser.InputBitrate != test.bitrate {
+				t.Errorf("format %s %d Hz %d kb/s, want %s %d Hz %d kb/s", parser.InputCodec, parser.InputSampleRate,
+					parser.InputBitrate, test.codec, test.rate, test.bitrate)
+			}
+			if lastError != test.lastError {
+				t.Errorf("error %s, want %s", lastError, test.lastError)
+			}
+		})
+	}
+}
+
+func TestProcessSplitsOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		lines  []string
+	}{
+		{
+			name:   "newlines",
+			output: "Input #0\nOutput #0\n",
+			lines:  []string{"Input #0", "Output #0"},
+		},
+		{
+			name:   "progress lines",
+			output: "size=1kB time=00:00:01\rsize=2kB time=00:00:02\r\nOutput #0",
+			lines:  []string{"size=1kB time=00:00:01", "size=2kB time=00:00:02", "Output #0"},
+		},
+		{
+			// Cut in pieces instead of stopping the scanner
+			name:   "line longer than the buffer",
+			output: strings.Repeat("x", MAX_OUTPUT_LINE+10) + "\nOutput #0\n",
+			lines:  []string{strings.Repeat("x", MAX_OUTPUT_LINE), strings.Repeat("x", 10), "Output #0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser := NewOutputParser()
+			err := parser.Process(strings.NewReader(test.output))
+			if err != nil {
+				t.Fatalf("Process failed: %s", err)
+			}
+			lines := parser.History.Lines()
+			if len(lines) != len(test.lines) {
+				t.Fatalf("got %d lines, want %d", len(lines), len(test.lines))
+			}
+			for i := range lines {
+				if lines[i] != test.lines[i] {
+					t.Errorf("line %d is %.40q, want %.40q", i, lines[i], test.lines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Periodic tasks against the station API. Every client polling on the exact same
+ * interval ends up hitting the API in sync, so all the delays get some random
+ * jitter to spread the load.
+ */
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// How often we check the station info
+const POLL_INTERVAL = 10 * time.Minute
+
+// How long after a track should have ended we ask for the next one, the API
+// needs a moment to notice the change
+const TRACK_END_DELAY = 3 * time.Second
+
+// In low data mode we poll this many times less often
+const LOW_DATA_POLL_FACTOR = 3
+
+// Fraction of the delay we randomly add or remove
+const POLL_JITTER = 0.1
+
+// Returns the delay randomly moved up to the jitter fraction up or down
+func jitteredDelay(delay time.Duration, jitter float64, rng *rand.Rand) time.Duration {
+	offset := (rng.Float64()*2 - 1) * jitter * float64(delay)
+	return delay + time.Duration(offset)
+}
+
+// Exponential backoff for the given attempt (starting at 0), capped to max and
+// jittered so clients that failed at the same time don't retry at the same time
+func backoffDelay(attempt int, base time.Duration, max time.Duration, rng *rand.Rand) time.Duration {
+	delay := float64(base) * math.Pow(2, float64(attempt))
+	if delay > float64(max) {
+		delay = float64(max)
+	}
+	return jitteredDelay(time.Duration(delay), POLL_JITTER, rng)
+}
+
+// Runs a task periodically in its own goroutine
+type Poller struct {
+	// Nanoseconds, it can be changed while running
+	interval atomic.Int64
+	task     func()
+	rng      *rand.Rand
+	refresh  chan bool
+	stop     chan bool
+}
+
+func NewPoller(interval time.Duration, rng *rand.Rand, task func()) *Poller {
+	poller := &Poller{
+		task:    task,
+		rng:     rng,
+		refresh: make(chan bool, 1),
+		stop:    make(chan bool),
+	}
+	poller.SetInterval(interval)
+	return poller
+}
+
+// Takes effect after the current wait
+func (poller *Poller) SetInterval(interval time.Duration) {
+	poller.interval.Store(int64(interval))
+}
+
+func (poller *Poller) Start() {
+	go func() {
+		for {
+			interval := time.Duration(poller.interval.Load())
+			timer := time.NewTimer(jitteredDelay(interval, POLL_JITTER, poller.rng))
+			select {
+			case <-timer.C:
+			case <-poller.refresh:
+				timer.Stop()
+			case <-poller.stop:
+				timer.Stop()
+				return
+			}
+			poller.task()
+		}
+	}()
+}
+
+// Runs the task as soon as possible, restarting the interval
+func (poller *Poller) Refresh() {
+	select {
+	case poller.refresh <- true:
+	default:
+		// There's already one refresh pending
+	}
+}
+
+func (poller *Poller) Stop() {
+	close(poller.stop)
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitteredDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		delay  time.Duration
+		jitter float64
+	}{
+		{name: "no jitter", delay: time.Minute, jitter: 0},
+		{name: "poll jitter", delay: POLL_INTERVAL, jitter: POLL_JITTER},
+		{name: "half", delay: 10 * time.Second, jitter: 0.5},
+		{name: "no delay", delay: 0, jitter: POLL_JITTER},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			again := rand.New(rand.NewSource(1))
+			spread := time.Duration(test.jitter * float64(test.delay))
+			for i := 0; i < 100; i++ {
+				delay := jitteredDelay(test.delay, test.jitter, rng)
+				if delay < test.delay-spread || delay > test.delay+spread {
+					t.Fatalf("delay %s out of %s ± %s", delay, test.delay, spread)
+				}
+				// The same seed gives the same delays
+				if same := jitteredDelay(test.delay, test.jitter, again); same != delay {
+					t.Fatalf("delay %s with the same seed, want %s", same, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		// Before the jitter
+		want time.Duration
+	}{
+		{name: "first attempt", attempt: 0, want: 5 * time.Second},
+		{name: "second attempt", attempt: 1, want: 10 * time.Second},
+		{name: "third attempt", attempt: 2, want: 20 * time.Second},
+		{name: "just under the cap", attempt: 5, want: 160 * time.Second},
+		{name: "capped", attempt: 6, want: 5 * time.Minute},
+		{name: "way past the cap", attempt: 40, want: 5 * time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(42))
+			spread := time.Duration(POLL_JITTER * float64(test.want))
+			for i := 0; i < 100; i++ {
+				delay := backoffDelay(test.attempt, 5*time.Second, 5*time.Minute, rng)
+				if delay < test.want-spread || delay > test.want+spread {
+					t.Fatalf("delay %s out of %s ± %s", delay, test.want, spread)
+				}
+			}
+		})
+	}
+}
@@ -19,26 +19,147 @@ package main
 
 /*
  * Small interface and object to grab ffmpeg and start streaming, piping the
- * raw wave output to Oto to send the audio back to the OS audio system
+ * raw wave output to an AudioSink (Oto, unless told otherwise) to send the audio
+ * back to the OS audio system
  *
  */
 
 import (
+	"encoding/base64"
+	"fmt"
 	"io"
 	"log"
 	"math"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
-
-	"github.com/ebitengine/oto/v3"
+	"sync"
+	"time"
 )
 
+// What we ask ffmpeg for, and what the audio device is opened with. They have
+// to match, or we play noise.
+const SAMPLE_RATE = 44100
+const CHANNEL_COUNT = 2
+const SAMPLE_CODEC = "pcm_s16le"
+
+// Ducking never takes the volume all the way down, so it isn't mistaken for
+// the player being muted
+const MIN_DUCK_FACTOR = 0.05
+
+// How long we wait for the audio output to close
+const SINK_CLOSE_TIMEOUT = 2 * time.Second
+
+// How often the volume goes up a notch while fading in
+const FADE_STEP = 50 * time.Millisecond
+
+// Size in bytes of half a second of our 44.1KHz 16 bit stereo audio, which is
+// also the default buffer oto gives to each player
+const PLAYER_BUFFER_SIZE = 44100 * 2 * 2 / 2
+
+// We won't grow the player buffer beyond four seconds of audio
+const MAX_PLAYER_BUFFER_SIZE = 8 * PLAYER_BUFFER_SIZE
+
+// On Windows we ship ffmpeg next to our executable, but it may also have been
+// installed on its own. We take the one next to us if it's there, else the
+// one in the PATH. If there's none we return the plain name and leave it to
+// checkPlayerAvailable to complain.
+func findPlayer() string {
+	name := "ffmpeg"
+	if runtime.GOOS == "windows" {
+		name = "ffmpeg.exe"
+	}
+
+	ex, err := os.Executable()
+	if err == nil {
+		sibling := filepath.Join(filepath.Dir(ex), name)
+		if info, err := os.Stat(sibling); err == nil && !info.IsDir() {
+			return sibling
+		}
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return name
+	}
+	return path
+}
+
+// Checks we can run the player binary before trying to play anything
+func checkPlayerAvailable(player_name string) error {
+	_, err := exec.LookPath(player_name)
+	if err != nil {
+		return fmt.Errorf("Couldn't find %s, please make sure it is installed next to the player or in your PATH", player_name)
+	}
+	return nil
+}
+
+// Takes the user and password out of the stream URL, ffmpeg gets them as an
+// Authorization header instead so they don't show up in its output. Returns the
+// URL without them and the headers for ffmpeg, empty if there are none.
+func splitStreamCredentials(stream_url string) (string, string) {
+	parsed, err := url.Parse(stream_url)
+	if err != nil || parsed.User == nil {
+		return stream_url, ""
+	}
+
+	password, _ := parsed.User.Password()
+	credentials := base64.StdEncoding.EncodeToString([]byte(parsed.User.Username() + ":" + password))
+	parsed.User = nil
+	return parsed.String(), "Authorization: Basic " + credentials + "\r\n"
+}
+
+// What we hand to ffmpeg as a stream, file is there for testing with local
+// recordings
+var STREAM_URL_SCHEMES = []string{"http", "https", "file"}
+
+// A stream URL we won't hand to ffmpeg
+type StreamURLError struct {
+	URL    string
+	Reason string
+}
+
+func (urlErr *StreamURLError) Error() string {
+	return fmt.Sprintf("Can't play the stream %q: %s", urlErr.URL, urlErr.Reason)
+}
+
+// Checks the stream URL is something ffmpeg can play, so a typo in the
+// settings gets a clear message instead of ffmpeg failing on it
+func validateStreamURL(stream_url string) error {
+	parsed, err := url.Parse(stream_url)
+	if err != nil {
+		return &StreamURLError{URL: redactURL(stream_url), Reason: "it isn't a valid URL"}
+	}
+	// Don't show the password of private streams around
+	shown := parsed.Redacted()
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme == "" {
+		return &StreamURLError{URL: shown, Reason: "it needs to start with http:// or https://"}
+	}
+	if !slices.Contains(STREAM_URL_SCHEMES, scheme) {
+		return &StreamURLError{URL: shown, Reason: scheme + " isn't supported, only http, https and file are"}
+	}
+	if scheme != "file" && parsed.Host == "" {
+		return &StreamURLError{URL: shown, Reason: "it has no host"}
+	}
+	return nil
+}
+
 // Radio player interface
 type RadioPlayer interface {
-	Load(stream_url string)
+	// The stream starts at the volume, from 0 to 1
+	Load(stream_url string, volume float64) error
 	IsPlaying() bool
 	IsMuted() bool
 	Play()
+	Pause()
+	Resume()
+	IsPaused() bool
 	Mute()
 	Stop()
 	IncVolume()
@@ -48,116 +169,454 @@ type RadioPlayer interface {
 
 // StreamPlayer
 type StreamPlayer struct {
-	player_name   string
-	stream_url    string
-	command       *exec.Cmd
-	in            io.WriteCloser
-	out           io.ReadCloser
-	audio         io.ReadCloser
-	otoContext    *oto.Context
-	otoPlayer     *oto.Player
+	player_name string
+	stream_url  string
+	command     *exec.Cmd
+	in          io.WriteCloser
+	out         io.ReadCloser
+	audio       io.ReadCloser
+	// Closed once command exited and was reaped
+	decoderDone chan struct{}
+	// Where the audio goes, oto if not set
+	sink          AudioSink
+	sinkOpen      bool
+	output        SinkPlayer
 	currentVolume float64
 	savedVolume   float64
+	bufferSize    int
+	paused        bool
+	// Buffer of the audio device, oto only allows one context for
+	// the whole process so this can't be changed once loaded
+	deviceBufferSize time.Duration
+	// Where else the audio goes, for recording or sending it elsewhere.
+	// Those that failed to open are left out of extraSinks.
+	ExtraSinks   []AudioSink
+	extraSinks   []AudioSink
+	extraQueues  []*teeQueue
+	extraOutputs []SinkPlayer
+	// Called when the audio from ffmpeg ends without us stopping it
+	OnAudioEnd func()
+	// Called with the audio on its way to the sink, in the goroutine of the
+	// sink, so it must be quick
+	OnAudioData func(data []byte)
+	// Called when the sink reads the first audio of a stream, which is when
+	// it starts being heard, not when ffmpeg starts its output
+	OnAudioStart func()
+	// Factors of the ducks in place, the last one is the newest. Whatever
+	// ducks may do it from any goroutine, duckMutex guards them.
+	duckMutex sync.Mutex
+	ducks     []float64
+	// Added to the volume, to even out louder and quieter stations
+	volumeOffset float64
+	// While fading in, the fraction of the volume we are at. A new fade or
+	// closing the stream bumps the generation, so an older fade stops.
+	// The fade runs in its own goroutine, fadeMutex guards them.
+	fadeMutex      sync.Mutex
+	fading         bool
+	fadeLevel      float64
+	fadeGeneration int
+	// Called when a fade in reaches the volume the user set
+	OnFadeEnd func()
+	// Ask ffmpeg for hardware accelerated decoding, unless it already
+	// failed us with it
+	HWAccel       bool
+	hwaccelFailed bool
+	usingHWAccel  bool
+	// player_name is mpv, which plays the stream itself instead of
+	// decoding it for us
+	External bool
 }
 
+// Not loaded yet (or stopped) is not playing, nothing wrong with that
 func (player *StreamPlayer) IsPlaying() bool {
-	if player.otoPlayer == nil {
-		log.Println("Player not loaded!")
+	if player.output == nil {
 		return false
 	}
 
-	return player.otoPlayer.IsPlaying()
+	return player.output.IsPlaying()
 }
 
-func (player *StreamPlayer) Load(stream_url string) {
-	if (player.otoPlayer == nil) || (!player.otoPlayer.IsPlaying()) {
-		var err error
-		is_playlist := strings.HasSuffix(stream_url, ".m3u") || strings.HasSuffix(stream_url, ".pls")
-		if is_playlist {
-			// TODO: Check ffmpeg's ability to deal with playlists
-			// player.command = exec.Command(player.player_name, "-quiet", "-playlist", stream_url)
-			player.command = exec.Command(player.player_name, "-nodisp", "-loglevel", "verbose", "-playlist", "-af", "pan=stereo|c0=c1|c1=c0", stream_url)
-		} else {
-			player.command = exec.Command(player.player_name, "-loglevel", "verbose", "-i", stream_url, "-f", "wav", "-af", "pan=stereo|c0=c1|c1=c0", "-")
+func (player *StreamPlayer) Load(stream_url string, volume float64) error {
+	err := validateStreamURL(stream_url)
+	if err != nil {
+		return err
+	}
+
+	// A paused stream still has its ffmpeg running, get rid of it first
+	if player.paused {
+		player.Close()
+	}
+
+	if player.External && (player.output == nil || !player.output.IsPlaying()) {
+		return player.loadExternal(stream_url, volume)
+	}
+
+	if (player.output == nil) || (!player.output.IsPlaying()) {
+		// Get the audio device before starting ffmpeg, there's no point in
+		// it running if we can't play anything
+		err := player.openAudioDevice()
+		if err != nil {
+			return err
 		}
 
-		// In to send things over stdin to ffmpeg
-		player.in, err = player.command.StdinPipe()
-		check(err)
-		// Out will be the wave data we will read and play
-		player.audio, err = player.command.StdoutPipe()
-		check(err)
+		input_url, headers := splitStreamCredentials(stream_url)
+		player.usingHWAccel = player.HWAccel && !player.hwaccelFailed
+		player.command = exec.Command(player.player_name, ffmpegArgs(input_url, headers, player.usingHWAccel)...)
+
+		// In to send things over stdin to ffmpeg. We don't need it to play,
+		// so we can do without it.
+		player.in = player.openStdin()
+		// Leaves nothing half open behind when ffmpeg can't be started
+		failed := func(err error) error {
+			if player.in != nil {
+				player.in.Close()
+				player.in = nil
+			}
+			if player.audio != nil {
+				player.audio.Close()
+				player.audio = nil
+			}
+			if player.out != nil {
+				player.out.Close()
+				player.out = nil
+			}
+			player.command = nil
+			return fmt.Errorf("Couldn't start %s: %w", player.player_name, err)
+		}
+		// Out will be the wave data we will read and play. Our own pipe, as
+		// Wait closes the ones of StdoutPipe before we read what's left.
+		audio, audioWriter, err := os.Pipe()
+		if err != nil {
+			return failed(err)
+		}
+		player.command.Stdout = audioWriter
+		var startOnce sync.Once
+		player.audio = newAudioReader(audio, func() {
+			if player.OnAudioEnd != nil {
+				player.OnAudioEnd()
+			}
+		}, func(data []byte) {
+			if player.OnAudioStart != nil {
+				startOnce.Do(func() { go player.OnAudioStart() })
+			}
+			if player.OnAudioData != nil {
+				player.OnAudioData(data)
+			}
+			for _, queue := range player.extraQueues {
+				queue.Push(data)
+			}
+		})
 		// Err is the output of ffmpeg, used to get stream title
-		player.out, err = player.command.StderrPipe()
-		check(err)
+		out, outWriter, err := os.Pipe()
+		if err != nil {
+			audioWriter.Close()
+			return failed(err)
+		}
+		player.command.Stderr = outWriter
+		player.out = out
 
-		log.Println("Starting ffmpeg")
-		err = player.command.Start()
-		check(err)
+		if player.usingHWAccel {
+			log.Println("Starting ffmpeg with hardware accelerated decoding")
+		} else {
+			log.Println("Starting ffmpeg with software decoding")
+		}
+		err = player.startDecoder()
+		// ffmpeg has its own copies, we only read
+		audioWriter.Close()
+		outWriter.Close()
+		if err != nil {
+			return failed(err)
+		}
 
 		player.stream_url = stream_url
 
-		op := &oto.NewContextOptions{
-			SampleRate:   44100,
-			ChannelCount: 2,
-			Format:       oto.FormatSignedInt16LE,
+		player.startExtraOutputs()
+		player.output = player.sink.NewPlayer(player.audio)
+		if player.bufferSize > 0 {
+			player.output.SetBufferSize(player.bufferSize)
+		} else {
+			player.bufferSize = PLAYER_BUFFER_SIZE
 		}
+		// A new output starts at full volume, set it before anything plays
+		player.currentVolume = volume
+		player.output.SetVolume(player.outputVolume(volume))
+	}
+	return nil
+}
 
-		if player.otoContext == nil {
-			otoContext, readyChan, err := oto.NewContext(op)
-			player.otoContext = otoContext
-			if err != nil {
-				log.Fatal(err)
-			}
-			<-readyChan
+// Arguments to decode the stream with ffmpeg into WAV on its stdout
+func ffmpegArgs(input_url string, headers string, hwaccel bool) []string {
+	// No progress stats, they are a never ending line of no use to us
+	args := []string{"-loglevel", "verbose", "-nostats"}
+	if headers != "" {
+		args = append(args, "-headers", headers)
+	}
+	if hwaccel {
+		args = append(args, "-hwaccel", "auto")
+	}
+	// ffmpeg would otherwise keep the stream's own format, which may be 24 bit
+	// or float, and oto expects 16 bit
+	format := []string{"-acodec", SAMPLE_CODEC, "-ar", strconv.Itoa(SAMPLE_RATE), "-ac", strconv.Itoa(CHANNEL_COUNT)}
+
+	is_playlist := strings.HasSuffix(input_url, ".m3u") || strings.HasSuffix(input_url, ".pls")
+	if is_playlist {
+		// TODO: Check ffmpeg's ability to deal with playlists
+		// player.command = exec.Command(player.player_name, "-quiet", "-playlist", stream_url)
+		args = append([]string{"-nodisp"}, args...)
+		args = append(args, "-playlist")
+		args = append(args, format...)
+		return append(args, "-af", "pan=stereo|c0=c1|c1=c0", input_url)
+	}
+	args = append(args, "-i", input_url)
+	args = append(args, format...)
+	return append(args, "-f", "wav", "-af", "pan=stereo|c0=c1|c1=c0", "-")
+}
+
+// Starts the command, and reaps it in the background once it exits
+func (player *StreamPlayer) startDecoder() error {
+	err := player.command.Start()
+	if err != nil {
+		return err
+	}
+	command := player.command
+	done := make(chan struct{})
+	player.decoderDone = done
+	go func() {
+		command.Wait()
+		close(done)
+	}()
+	return nil
+}
+
+// Closed once the command of the current stream exited, nil if there's none
+func (player *StreamPlayer) DecoderDone() <-chan struct{} {
+	return player.decoderDone
+}
+
+// Pipe to the stdin of the command, nil if we couldn't get one
+func (player *StreamPlayer) openStdin() io.WriteCloser {
+	in, err := player.command.StdinPipe()
+	if err != nil {
+		log.Printf("Couldn't open the stdin of %s, going on without it: %s", player.player_name, err)
+		return nil
+	}
+	return in
+}
+
+// Starts mpv on the stream. Its output goes to out, just like ffmpeg's, but
+// there is no audio for us.
+func (player *StreamPlayer) loadExternal(stream_url string, volume float64) error {
+	input_url, headers := splitStreamCredentials(stream_url)
+	// The socket isn't there until mpv is up, so it starts at our volume
+	// instead of being told later
+	startVolume := player.outputVolume(volume)
+	socket := mpvSocketPath()
+	player.command = exec.Command(player.player_name, mpvArgs(input_url, strings.TrimSuffix(headers, "\r\n"), socket, startVolume)...)
+
+	player.in = player.openStdin()
+	// mpv says what's going on in both stdout and stderr, so both go to out
+	out, outWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	player.command.Stdout = outWriter
+	player.command.Stderr = outWriter
+	player.out = out
+	player.audio = nil
+
+	log.Printf("Starting %s", player.player_name)
+	err = player.startDecoder()
+	// mpv has its own copy, we only read
+	outWriter.Close()
+	if err != nil {
+		out.Close()
+		player.out = nil
+		return err
+	}
+
+	player.stream_url = stream_url
+	player.output = newMPVPlayer(socket, startVolume)
+	player.currentVolume = volume
+	return nil
+}
+
+// Each load tries to open the sink again if the last attempt failed, it's up
+// to the sink whether it can (oto mostly can't, see OtoSink)
+func (player *StreamPlayer) openAudioDevice() error {
+	if player.sinkOpen {
+		return nil
+	}
+
+	if player.sink == nil {
+		player.sink = &OtoSink{}
+	}
+	options := AudioSinkOptions{
+		SampleRate:   SAMPLE_RATE,
+		ChannelCount: CHANNEL_COUNT,
+		BufferSize:   player.deviceBufferSize,
+	}
+	err := player.sink.Open(options)
+	if err != nil {
+		log.Printf("Couldn't open the audio device: %s", err)
+		return &AudioDeviceError{Err: err}
+	}
+
+	player.sinkOpen = true
+
+	// The extra sinks are nice to have, we play without the ones that fail
+	for _, sink := range player.ExtraSinks {
+		err := sink.Open(options)
+		if err != nil {
+			log.Printf("Couldn't open an extra audio sink, going on without it: %s", err)
+			continue
 		}
+		player.extraSinks = append(player.extraSinks, sink)
+	}
+	return nil
+}
+
+// Gives each extra sink its queue of the audio of the new stream
+func (player *StreamPlayer) startExtraOutputs() {
+	// A stream that ended on its own may have left its outputs behind
+	player.closeExtraOutputs()
+	for _, sink := range player.extraSinks {
+		queue := newTeeQueue()
+		output := sink.NewPlayer(queue)
+		output.Play()
+		player.extraQueues = append(player.extraQueues, queue)
+		player.extraOutputs = append(player.extraOutputs, output)
+	}
+}
 
-		player.otoPlayer = player.otoContext.NewPlayer(player.audio)
-		// Save current volume for the mute function
-		player.currentVolume = player.otoPlayer.Volume()
+func (player *StreamPlayer) closeExtraOutputs() {
+	for i := range player.extraOutputs {
+		player.extraQueues[i].Close()
+		player.extraOutputs[i].Close()
 	}
+	player.extraQueues = nil
+	player.extraOutputs = nil
 }
 
 func (player *StreamPlayer) Play() {
-	if player.otoPlayer == nil {
+	if player.output == nil {
 		log.Println("Stream not loaded")
 		return
 	}
 
-	if !player.otoPlayer.IsPlaying() {
+	if !player.output.IsPlaying() {
 		if player.command == nil {
-			player.Load(player.stream_url)
+			player.Load(player.stream_url, player.currentVolume)
+		}
+		player.output.Play()
+		// A new output starts at full volume, without the adjustments
+		if player.volumeOffset != 0 || player.IsDucked() || player.isFading() {
+			player.SetVolume(player.currentVolume)
 		}
-		player.otoPlayer.Play()
 	}
 }
 
 func (player *StreamPlayer) Close() {
-	if player.IsPlaying() {
-		err := player.otoPlayer.Close()
-		if err != nil {
-			log.Println(err)
+	player.fadeMutex.Lock()
+	player.fadeGeneration++
+	player.fading = false
+	player.fadeMutex.Unlock()
+
+	// Nothing loaded, or already closed
+	if player.output == nil && player.command == nil {
+		return
+	}
+
+	// Whether it's playing or not doesn't matter: a stream that dropped has
+	// its output drained, but the pipes and the dead ffmpeg are still around
+	player.paused = false
+
+	// Some audio backends get stuck closing, that shouldn't hang the
+	// player, or the app on its way out
+	if player.output != nil {
+		output := player.output
+		closed := make(chan error, 1)
+		go func() {
+			closed <- output.Close()
+		}()
+		select {
+		case err := <-closed:
+			if err != nil {
+				log.Println(err)
+			}
+		case <-time.After(SINK_CLOSE_TIMEOUT):
+			log.Printf("The audio output didn't close in %s, going on without waiting", SINK_CLOSE_TIMEOUT)
 		}
+		player.output = nil
+	}
+	if player.in != nil {
 		player.in.Close()
+		player.in = nil
+	}
+	if player.out != nil {
 		player.out.Close()
-		player.audio.Close()
 		player.out = nil
+	}
+	if player.audio != nil {
+		player.audio.Close()
+		player.audio = nil
+	}
+	player.closeExtraOutputs()
+
+	// Closing the pipes should be enough for ffmpeg to finish, but make
+	// sure it is gone and reaped so we don't leave a zombie behind
+	if player.command != nil && player.command.Process != nil {
+		player.command.Process.Kill()
+		if player.decoderDone != nil {
+			<-player.decoderDone
+		}
+	}
+	player.command = nil
+	player.decoderDone = nil
+
+	player.stream_url = ""
+}
+
+// Whether the running ffmpeg was asked for hardware accelerated decoding
+func (player *StreamPlayer) UsingHWAccel() bool {
+	return player.usingHWAccel
+}
 
-		player.stream_url = ""
+// ffmpeg didn't get along with hardware acceleration, from now on we decode
+// in software
+func (player *StreamPlayer) DisableHWAccel() {
+	player.hwaccelFailed = true
+}
+
+// Kills ffmpeg but leaves the player as it is, so the stream ends just like
+// when the connection drops. Used when ffmpeg is stuck on a broken stream.
+func (player *StreamPlayer) KillDecoder() {
+	if player.command != nil && player.command.Process != nil {
+		player.command.Process.Kill()
 	}
 }
 
+// Muted is about the volume the user set, not what we hear right now: while
+// fading in or ducked the output is quieter than that
 func (player *StreamPlayer) IsMuted() bool {
-	return player.otoPlayer.Volume() == 0.0
+	if player.output == nil {
+		return false
+	}
+
+	return player.curvedVolume(player.currentVolume) == 0.0
 }
 
 func (player *StreamPlayer) Mute() {
 	if player.IsPlaying() {
-		if player.otoPlayer.Volume() > 0 {
+		if !player.IsMuted() {
 			player.savedVolume = player.currentVolume
 			player.currentVolume = 0.0
 			player.SetVolume(0.0)
 		} else {
+			// If we got to silence through the volume down button there's
+			// no saved volume, so bring it back to something audible
+			if player.savedVolume <= 0.0 {
+				player.savedVolume = 0.5
+			}
 			player.currentVolume = player.savedVolume
 			player.SetVolume(player.savedVolume)
 		}
@@ -165,48 +624,210 @@ func (player *StreamPlayer) Mute() {
 }
 
 func (player *StreamPlayer) Stop() {
+	player.Close()
+}
+
+// Pauses the audio, but ffmpeg keeps the connection. Whatever is buffered
+// by then is what we will hear when resuming, not the live stream.
+func (player *StreamPlayer) Pause() {
 	if player.IsPlaying() {
-		player.Close()
+		player.output.Pause()
+		player.paused = true
+	}
+}
+
+func (player *StreamPlayer) Resume() {
+	if player.paused {
+		player.output.Play()
+		player.paused = false
 	}
 }
 
+func (player *StreamPlayer) IsPaused() bool {
+	return player.paused
+}
+
 func (player *StreamPlayer) IncVolume() {
 	if player.IsPlaying() {
-		player.currentVolume += 0.05
+		player.currentVolume = math.Min(player.currentVolume+0.05, 1.0)
 		player.SetVolume(player.currentVolume)
 	}
 }
 
 func (player *StreamPlayer) DecVolume() {
 	if player.IsPlaying() {
-		player.currentVolume -= 0.05
+		player.currentVolume = math.Max(player.currentVolume-0.05, 0.0)
 		player.SetVolume(player.currentVolume)
 	}
 }
 
+// The volume of the output for the one the user set, before ducking or
+// fading. Linear for mpv, which has a curve of its own.
+func (player *StreamPlayer) curvedVolume(volume float64) float64 {
+	// Muted is muted, whatever the station
+	if volume > 0.0 {
+		volume += player.volumeOffset
+	}
+	if volume > 1.0 {
+		return 1.0
+	} else if volume < 0.0 {
+		return 0.0
+	}
+	// mpv puts the volume on its own curve, it gets the slider as it is
+	if player.External {
+		return volume
+	}
+	// We make the volume exponential so it decreases
+	// in a way the human ear really feels it
+	// expVolume := math.Exp(4*volume - 4)
+	expVolume := math.Pow(volume, 2)
+	if expVolume < 0.1 {
+		return 0.0
+	}
+	return expVolume
+}
+
+// What the output plays at for the volume the user set, with the ducks and
+// the fade in place
+func (player *StreamPlayer) outputVolume(volume float64) float64 {
+	return player.curvedVolume(volume) * player.duckFactor() * player.fadeFactor()
+}
+
 func (player *StreamPlayer) SetVolume(volume float64) {
 	if player.IsPlaying() {
-		if volume > 1.0 {
-			player.otoPlayer.SetVolume(1.0)
-		} else if volume < 0.0 {
-			player.otoPlayer.SetVolume(0.0)
-		} else {
-			// We make the volume exponential so it decreases
-			// in a way the human ear really feels it
-			// expVolume := math.Exp(4*volume - 4)
-			expVolume := math.Pow(volume, 2)
-			if expVolume < 0.1 {
-				expVolume = 0.0
+		player.output.SetVolume(player.outputVolume(volume))
+	}
+}
+
+// Sets how much louder or quieter than the user volume we play, and applies
+// it right away
+func (player *StreamPlayer) SetVolumeOffset(offset float64) {
+	player.volumeOffset = offset
+	player.SetVolume(player.currentVolume)
+}
+
+// Lowers the volume to the given fraction of what the user set, until Unduck
+// is called. Ducks can overlap: the deepest one in place is the one we hear,
+// and each Unduck takes away the newest one.
+func (player *StreamPlayer) Duck(factor float64) {
+	player.duckMutex.Lock()
+	player.ducks = append(player.ducks, math.Max(MIN_DUCK_FACTOR, math.Min(factor, 1.0)))
+	player.duckMutex.Unlock()
+	player.SetVolume(player.currentVolume)
+}
+
+func (player *StreamPlayer) Unduck() {
+	player.duckMutex.Lock()
+	if len(player.ducks) == 0 {
+		player.duckMutex.Unlock()
+		return
+	}
+	player.ducks = player.ducks[:len(player.ducks)-1]
+	player.duckMutex.Unlock()
+	player.SetVolume(player.currentVolume)
+}
+
+func (player *StreamPlayer) IsDucked() bool {
+	player.duckMutex.Lock()
+	defer player.duckMutex.Unlock()
+	return len(player.ducks) > 0
+}
+
+func (player *StreamPlayer) duckFactor() float64 {
+	player.duckMutex.Lock()
+	defer player.duckMutex.Unlock()
+	factor := 1.0
+	for _, duck := range player.ducks {
+		factor = math.Min(factor, duck)
+	}
+	return factor
+}
+
+// Keeps the next stream silent until FadeIn, so it isn't heard at full volume
+// before the fade starts
+func (player *StreamPlayer) HoldForFade() {
+	player.fadeMutex.Lock()
+	defer player.fadeMutex.Unlock()
+
+	player.fadeGeneration++
+	player.fading = true
+	player.fadeLevel = 0.0
+}
+
+// Raises the volume from silence to the one the user set over the duration.
+// Changing the volume meanwhile just changes where the fade ends up.
+func (player *StreamPlayer) FadeIn(duration time.Duration) {
+	if !player.isFading() {
+		player.HoldForFade()
+		player.SetVolume(player.currentVolume)
+	}
+	player.fadeMutex.Lock()
+	generation := player.fadeGeneration
+	player.fadeMutex.Unlock()
+
+	go func() {
+		steps := max(int(duration/FADE_STEP), 1)
+		for step := 1; step <= steps; step++ {
+			time.Sleep(duration / time.Duration(steps))
+			player.fadeMutex.Lock()
+			if player.fadeGeneration != generation {
+				player.fadeMutex.Unlock()
+				return
+			}
+			player.fadeLevel = float64(step) / float64(steps)
+			if step == steps {
+				player.fading = false
 			}
-			player.otoPlayer.SetVolume(expVolume)
+			player.fadeMutex.Unlock()
+			player.SetVolume(player.currentVolume)
+		}
+		if player.OnFadeEnd != nil {
+			player.OnFadeEnd()
 		}
+	}()
+}
+
+func (player *StreamPlayer) isFading() bool {
+	player.fadeMutex.Lock()
+	defer player.fadeMutex.Unlock()
+	return player.fading
+}
+
+func (player *StreamPlayer) fadeFactor() float64 {
+	player.fadeMutex.Lock()
+	defer player.fadeMutex.Unlock()
+	if player.fading {
+		return player.fadeLevel
 	}
+	return 1.0
 }
 
 func (player *StreamPlayer) GetVolume() float64 {
 	if player.IsPlaying() {
-		return player.otoPlayer.Volume()
+		return player.output.Volume()
 	} else {
 		return 0.0
 	}
 }
+
+// How full the player buffer is, from 0.0 (drained) to 1.0 (full)
+func (player *StreamPlayer) BufferHealth() float64 {
+	// Polled all the time, even before anything is loaded
+	if player.output == nil || !player.IsPlaying() || player.bufferSize == 0 {
+		return 0.0
+	}
+
+	return math.Min(float64(player.output.BufferedSize())/float64(player.bufferSize), 1.0)
+}
+
+// Doubles the player buffer, so we are more resilient to network hiccups at
+// the cost of some latency. Returns false if we are already at the maximum.
+func (player *StreamPlayer) WidenBuffer() bool {
+	if !player.IsPlaying() || player.bufferSize >= MAX_PLAYER_BUFFER_SIZE {
+		return false
+	}
+
+	player.bufferSize = min(player.bufferSize*2, MAX_PLAYER_BUFFER_SIZE)
+	player.output.SetBufferSize(player.bufferSize)
+	return true
+}
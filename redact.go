@@ -0,0 +1,55 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Stream URLs can carry credentials or tokens, and ffmpeg happily prints them.
+ * Everything that ends up in the log or in the diagnostics goes through here
+ * first so they don't leak in a bug report.
+ */
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Anything looking like a URL in a line of text
+var URL_PATTERN = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s'"<>]+`)
+
+// The URL without user info, query parameters or fragment
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		// Can't tell what's in there, so keep nothing but the scheme
+		scheme, _, _ := strings.Cut(rawURL, "://")
+		return scheme + "://[redacted]"
+	}
+
+	parsed.User = nil
+	parsed.RawQuery = ""
+	parsed.ForceQuery = false
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String()
+}
+
+// Redacts all the URLs found in the text
+func redactURLs(text string) string {
+	return URL_PATTERN.ReplaceAllStringFunc(text, redactURL)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * The images bundled in bundle.go. They are compiled in, so they are always
+ * there unless someone customizing the build swaps them for something broken:
+ * in that case we fall back to plain defaults instead of crashing.
+ */
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+)
+
+// The resource if it's usable, or else the fallback
+func bundledResource(resource *fyne.StaticResource, fallback fyne.Resource) fyne.Resource {
+	if resource == nil || len(resource.Content()) == 0 {
+		log.Println("Missing bundled resource, using a default one")
+		return fallback
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(resource.Content())); err != nil {
+		log.Printf("Broken bundled resource %s: %s", resource.Name(), err)
+		return fallback
+	}
+	return resource
+}
+
+func appIcon() fyne.Resource {
+	return bundledResource(resourceIconPng, theme.FyneLogo())
+}
+
+// The RadioSpiral header, or just the name if the image is broken
+func headerImage() fyne.CanvasObject {
+	resource := bundledResource(resourceHeaderPng, nil)
+	if resource == nil {
+		title := canvas.NewText("RadioSpiral", theme.Color(theme.ColorNameForeground))
+		title.TextSize = 32
+		title.TextStyle = fyne.TextStyle{Bold: true}
+		title.Alignment = fyne.TextAlignCenter
+		return title
+	}
+
+	header := canvas.NewImageFromResource(resource)
+	header.SetMinSize(fyne.NewSize(400, 120))
+	header.FillMode = canvas.ImageFillContain
+	return header
+}
+
+// The RadioSpiral logo as an image, a plain gray square if it's broken
+func iconImage() image.Image {
+	img, _, err := image.Decode(bytes.NewReader(appIcon().Content()))
+	if err != nil {
+		log.Printf("Couldn't decode the icon: %s", err)
+		fallback := image.NewRGBA(image.Rect(0, 0, 200, 200))
+		draw.Draw(fallback, fallback.Bounds(), image.NewUniform(color.Gray{Y: 0x80}), image.Point{}, draw.Src)
+		return fallback
+	}
+	return img
+}
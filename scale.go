@@ -0,0 +1,58 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Bigger (or smaller) text and controls, for high resolution screens or for
+ * those who find the default too small. Fyne takes its scale from the system,
+ * so we scale the sizes of the theme instead, which also applies right away.
+ */
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// The default theme with all its sizes scaled
+type scaledTheme struct {
+	base  fyne.Theme
+	scale float32
+}
+
+// Scale in percent, 100 is the default size
+func newScaledTheme(percent int) fyne.Theme {
+	return &scaledTheme{base: theme.DefaultTheme(), scale: float32(percent) / 100}
+}
+
+func (scaled *scaledTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	return scaled.base.Color(name, variant)
+}
+
+func (scaled *scaledTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return scaled.base.Font(style)
+}
+
+func (scaled *scaledTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return scaled.base.Icon(name)
+}
+
+func (scaled *scaledTheme) Size(name fyne.ThemeSizeName) float32 {
+	return scaled.base.Size(name) * scaled.scale
+}
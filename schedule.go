@@ -0,0 +1,91 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Picking shows out of the station schedule. The schedule endpoint doesn't
+ * promise any order, so we sort it by start time before looking at it.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Sorts the shows by start time, in place
+func sortSchedule(shows []BroadcastResponse) {
+	sort.SliceStable(shows, func(i, j int) bool {
+		return shows[i].StartTime < shows[j].StartTime
+	})
+}
+
+// The show on air: the one flagged as such, or else the latest one that has
+// already started. Nil if there's none. The shows must be sorted.
+func currentShow(shows []BroadcastResponse, now time.Time) *BroadcastResponse {
+	for i := range shows {
+		if shows[i].IsNow {
+			return &shows[i]
+		}
+	}
+
+	var current *BroadcastResponse
+	for i := range shows {
+		if int64(shows[i].StartTime) > now.Unix() {
+			break
+		}
+		current = &shows[i]
+	}
+	return current
+}
+
+// The first show that hasn't started yet, nil if there's none. The shows must
+// be sorted.
+func nextShow(shows []BroadcastResponse, now time.Time) *BroadcastResponse {
+	for i := range shows {
+		if int64(shows[i].StartTime) > now.Unix() && !shows[i].IsNow {
+			return &shows[i]
+		}
+	}
+	return nil
+}
+
+// How the show is called, the title is the nicer one if there's one
+func (show *BroadcastResponse) DisplayName() string {
+	if show.Title != "" {
+		return show.Title
+	}
+	return show.Name
+}
+
+// Time left as 04:32, with the hours and days in front when there are any
+func formatCountdown(left time.Duration) string {
+	seconds := int64(max(left, 0).Round(time.Second) / time.Second)
+	days := seconds / 86400
+	hours := seconds / 3600 % 24
+	minutes := seconds / 60 % 60
+	seconds %= 60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd %02d:%02d:%02d", days, hours, minutes, seconds)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%02d:%02d", minutes, seconds)
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// A day of shows, given out of order like the API may
+func testSchedule() []BroadcastResponse {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	return []BroadcastResponse{
+		{Name: "Evening drift", StartTime: FlexInt(day + 20*3600)},
+		{Name: "Morning ambient", StartTime: FlexInt(day + 8*3600)},
+		{Name: "Afternoon space", StartTime: FlexInt(day + 14*3600)},
+	}
+}
+
+func TestSortSchedule(t *testing.T) {
+	shows := testSchedule()
+	sortSchedule(shows)
+
+	want := []string{"Morning ambient", "Afternoon space", "Evening drift"}
+	for i, show := range shows {
+		if show.Name != want[i] {
+			t.Errorf("show %d is %q, want %q", i, show.Name, want[i])
+		}
+	}
+}
+
+func TestCurrentAndNextShow(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2024, 3, 1, hour, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		// Which show is flagged on air by the API, -1 for none
+		flagged int
+		current string
+		next    string
+	}{
+		{name: "before the first show", now: at(6), flagged: -1, current: "", next: "Morning ambient"},
+		{name: "as a show starts", now: at(8), flagged: -1, current: "Morning ambient", next: "Afternoon space"},
+		{name: "between shows", now: at(16), flagged: -1, current: "Afternoon space", next: "Evening drift"},
+		{name: "last show of the day", now: at(23), flagged: -1, current: "Evening drift", next: ""},
+		{name: "flagged by the API", now: at(16), flagged: 0, current: "Morning ambient", next: "Evening drift"},
+	}
+
+	name := func(show *BroadcastResponse) string {
+		if show == nil {
+			return ""
+		}
+		return show.Name
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shows := testSchedule()
+			sortSchedule(shows)
+			if test.flagged >= 0 {
+				shows[test.flagged].IsNow = true
+			}
+
+			if current := name(currentShow(shows, test.now)); current != test.current {
+				t.Errorf("current show %q, want %q", current, test.current)
+			}
+			if next := name(nextShow(shows, test.now)); next != test.next {
+				t.Errorf("next show %q, want %q", next, test.next)
+			}
+		})
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	tests := []struct {
+		left time.Duration
+		want string
+	}{
+		{left: 0, want: "00:00"},
+		{left: -time.Minute, want: "00:00"},
+		{left: 4*time.Minute + 32*time.Second, want: "04:32"},
+		{left: 59*time.Minute + 59*time.Second, want: "59:59"},
+		// Rounds up into the next hour
+		{left: 59*time.Minute + 59*time.Second + 600*time.Millisecond, want: "1:00:00"},
+		{left: time.Hour + time.Second, want: "1:00:01"},
+		{left: 26*time.Hour + 3*time.Minute, want: "1d 02:03:00"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := formatCountdown(test.left); got != test.want {
+				t.Errorf("formatCountdown(%s) = %q, want %q", test.left, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Dead air detection, for hosts keeping an eye on their own stream. We measure
+ * the level of the audio on its way to the sound card, and if it stays below
+ * the threshold long enough we let them know. Quiet passages are a thing in
+ * ambient music, so the window has to be long: half a minute at least.
+ */
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+// Full scale of our 16 bit samples
+const FULL_SCALE = 32768.0
+
+type SilenceDetector struct {
+	mutex sync.Mutex
+	// Linear level under which the audio counts as silence
+	threshold float64
+	// Samples (per channel) of silence before we call it dead air
+	limit int
+	// How much silence we have seen in a row, in samples per channel
+	quiet int
+	// Whether we already told about this silence
+	silent bool
+	// Half a sample left from the last read, reads don't have to be aligned
+	carry    []byte
+	OnSilent func()
+	OnSound  func()
+}
+
+func NewSilenceDetector(thresholdDB int, duration time.Duration) *SilenceDetector {
+	detector := &SilenceDetector{}
+	detector.Configure(thresholdDB, duration)
+	return detector
+}
+
+func (detector *SilenceDetector) Configure(thresholdDB int, duration time.Duration) {
+	detector.mutex.Lock()
+	defer detector.mutex.Unlock()
+
+	detector.threshold = FULL_SCALE * math.Pow(10, float64(thresholdDB)/20)
+	detector.limit = int(duration.Seconds() * SAMPLE_RATE)
+}
+
+// Starts counting again, for a new stream
+func (detector *SilenceDetector) Reset() {
+	detector.mutex.Lock()
+	wasSilent := detector.silent
+	detector.quiet = 0
+	detector.silent = false
+	detector.carry = nil
+	detector.mutex.Unlock()
+
+	if wasSilent && detector.OnSound != nil {
+		detector.OnSound()
+	}
+}
+
+// Takes a look at the 16 bit little endian stereo audio
+func (detector *SilenceDetector) Feed(data []byte) {
+	detector.mutex.Lock()
+
+	if len(detector.carry) > 0 {
+		data = append(detector.carry, data...)
+		detector.carry = nil
+	}
+	if len(data)%2 != 0 {
+		detector.carry = []byte{data[len(data)-1]}
+		data = data[:len(data)-1]
+	}
+	samples := len(data) / 2
+	if samples == 0 {
+		detector.mutex.Unlock()
+		return
+	}
+
+	var sum float64
+	for i := 0; i+1 < len(data); i += 2 {
+		sample := float64(int16(binary.LittleEndian.Uint16(data[i:])))
+		sum += sample * sample
+	}
+	rms := math.Sqrt(sum / float64(samples))
+
+	var notify func()
+	if rms < detector.threshold {
+		detector.quiet += samples / CHANNEL_COUNT
+		if !detector.silent && detector.quiet >= detector.limit {
+			detector.silent = true
+			notify = detector.OnSilent
+		}
+	} else {
+		detector.quiet = 0
+		if detector.silent {
+			detector.silent = false
+			notify = detector.OnSound
+		}
+	}
+	detector.mutex.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Where the decoded audio goes. StreamPlayer only talks to an AudioSink, oto is
+ * the one we use, but we can swap it for something else where oto misbehaves,
+ * or for a sink that plays nothing at all when working on the player.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/oto/v3"
+)
+
+type AudioSinkOptions struct {
+	SampleRate   int
+	ChannelCount int
+	// Buffer of the device, 0 for the default of the sink
+	BufferSize time.Duration
+}
+
+type AudioSink interface {
+	// Gets the device ready, called once before the first NewPlayer
+	Open(options AudioSinkOptions) error
+	// Plays the 16 bit little endian audio coming from the reader
+	NewPlayer(reader io.Reader) SinkPlayer
+}
+
+// Playback of a stream on a sink, the methods of oto.Player
+type SinkPlayer interface {
+	Play()
+	Pause()
+	IsPlaying() bool
+	Volume() float64
+	SetVolume(volume float64)
+	BufferedSize() int
+	SetBufferSize(bufferSize int)
+	Close() error
+}
+
+// oto only allows one context for the whole process, even when creating it
+// failed, so once it failed only a new process can try again
+var ErrAudioDeviceNeedsRestart = errors.New("the audio device can only be opened once each run")
+
+// The audio device through oto. Each Open tries again until there's a working
+// context: a context whose device failed may come around, and oto may let us
+// create one after all. When it doesn't, we say so along with the first error,
+// which is the one that tells what's wrong.
+type OtoSink struct {
+	context *oto.Context
+	// Created, but its device failed
+	failed   *oto.Context
+	firstErr error
+}
+
+func (sink *OtoSink) Open(options AudioSinkOptions) error {
+	if sink.context != nil {
+		return nil
+	}
+	if sink.failed != nil {
+		return sink.checkDevice(sink.failed)
+	}
+
+	op := &oto.NewContextOptions{
+		SampleRate:   options.SampleRate,
+		ChannelCount: options.ChannelCount,
+		Format:       oto.FormatSignedInt16LE,
+		BufferSize:   options.BufferSize,
+	}
+	context, readyChan, err := oto.NewContext(op)
+	if err != nil {
+		if sink.firstErr == nil {
+			sink.firstErr = err
+			return err
+		}
+		return fmt.Errorf("%w, restart the player to try again (%w)", ErrAudioDeviceNeedsRestart, sink.firstErr)
+	}
+	<-readyChan
+	return sink.checkDevice(context)
+}
+
+// Some backends only fail once they try to open the device
+func (sink *OtoSink) checkDevice(context *oto.Context) error {
+	err := context.Err()
+	if err != nil {
+		sink.failed = context
+		if sink.firstErr == nil {
+			sink.firstErr = err
+		}
+		return err
+	}
+
+	sink.context = context
+	sink.failed = nil
+	return nil
+}
+
+func (sink *OtoSink) NewPlayer(reader io.Reader) SinkPlayer {
+	return sink.context.NewPlayer(reader)
+}
+
+// Plays nothing, but reads the audio as fast as it would be played
+type SilentSink struct {
+	// Bytes of audio per second
+	byteRate int
+}
+
+func (sink *SilentSink) Open(options AudioSinkOptions) error {
+	sink.byteRate = options.SampleRate * options.ChannelCount * 2
+	return nil
+}
+
+func (sink *SilentSink) NewPlayer(reader io.Reader) SinkPlayer {
+	return &silentPlayer{
+		reader:     reader,
+		byteRate:   sink.byteRate,
+		volume:     1.0,
+		bufferSize: PLAYER_BUFFER_SIZE,
+	}
+}
+
+// How often the silent player reads
+const SILENT_PLAYER_TICK = 100 * time.Millisecond
+
+type silentPlayer struct {
+	mutex      sync.Mutex
+	reader     io.Reader
+	byteRate   int
+	volume     float64
+	bufferSize int
+	playing    bool
+	closed     bool
+	// Running while playing, closed to stop it
+	stop chan struct{}
+}
+
+func (player *silentPlayer) Play() {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	if player.playing || player.closed {
+		return
+	}
+	player.playing = true
+	player.stop = make(chan struct{})
+	go player.drain(player.stop)
+}
+
+func (player *silentPlayer) drain(stop chan struct{}) {
+	chunk := make([]byte, player.byteRate*int(SILENT_PLAYER_TICK)/int(time.Second))
+	ticker := time.NewTicker(SILENT_PLAYER_TICK)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_, err := io.ReadFull(player.reader, chunk)
+			if err != nil {
+				player.mutex.Lock()
+				if player.stop == stop {
+					player.playing = false
+				}
+				player.mutex.Unlock()
+				return
+			}
+		}
+	}
+}
+
+func (player *silentPlayer) Pause() {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	if player.playing {
+		close(player.stop)
+		player.playing = false
+	}
+}
+
+func (player *silentPlayer) IsPlaying() bool {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.playing
+}
+
+func (player *silentPlayer) Volume() float64 {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.volume
+}
+
+func (player *silentPlayer) SetVolume(volume float64) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.volume = volume
+}
+
+// Nothing to play means nothing waiting to be played, but we report a full
+// buffer so nobody thinks we are starving
+func (player *silentPlayer) BufferedSize() int {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.bufferSize
+}
+
+func (player *silentPlayer) SetBufferSize(bufferSize int) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.bufferSize = bufferSize
+}
+
+func (player *silentPlayer) Close() error {
+	player.Pause()
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.closed = true
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Keeping the system awake while we play, so a laptop doesn't go to sleep in
+ * the middle of a long listening session. Each platform has its own way, the
+ * GUI only sees the sleepInhibitor interface. When it sleeps anyway, we notice
+ * once it wakes up.
+ */
+
+import (
+	"time"
+)
+
+// How often we look at the clock, and how much longer than that a tick can
+// take before we take it as the system having slept
+const RESUME_CHECK_INTERVAL = 5 * time.Second
+const RESUME_GAP = 30 * time.Second
+
+type sleepInhibitor interface {
+	// Keeps the system from sleeping until Release is called
+	Inhibit() error
+	Release()
+}
+
+// For platforms we don't know how to keep awake
+type noSleepInhibitor struct{}
+
+func (noSleepInhibitor) Inhibit() error { return nil }
+func (noSleepInhibitor) Release()       {}
+
+// Calls onResume each time the system wakes up, for as long as running says
+// so. Our sleeps stop with the system while the wall clock goes on, so a tick
+// that took much longer on the wall clock than it should means we slept.
+func watchForResume(running func() bool, onResume func(asleep time.Duration)) {
+	// Round drops the monotonic reading, so we compare wall clock times
+	last := time.Now().Round(0)
+	for running() {
+		time.Sleep(RESUME_CHECK_INTERVAL)
+		now := time.Now().Round(0)
+		elapsed := now.Sub(last)
+		last = now
+		if elapsed > RESUME_CHECK_INTERVAL+RESUME_GAP {
+			onResume(elapsed - RESUME_CHECK_INTERVAL)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+//go:build darwin
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+#cgo LDFLAGS: -framework IOKit -framework CoreFoundation
+#include <IOKit/pwr_mgt/IOPMLib.h>
+
+static int preventSleep(IOPMAssertionID *assertion) {
+	return IOPMAssertionCreateWithName(kIOPMAssertionTypePreventUserIdleSystemSleep,
+		kIOPMAssertionLevelOn, CFSTR("Playing the radio"), assertion) == kIOReturnSuccess;
+}
+*/
+import "C"
+
+import "errors"
+
+// An IOKit power assertion, held until released
+type iokitInhibitor struct {
+	assertion C.IOPMAssertionID
+	active    bool
+}
+
+func newSleepInhibitor() sleepInhibitor {
+	return &iokitInhibitor{}
+}
+
+func (inhibitor *iokitInhibitor) Inhibit() error {
+	if inhibitor.active {
+		return nil
+	}
+
+	if C.preventSleep(&inhibitor.assertion) == 0 {
+		return errors.New("IOKit refused the power assertion")
+	}
+	inhibitor.active = true
+	return nil
+}
+
+func (inhibitor *iokitInhibitor) Release() {
+	if inhibitor.active {
+		C.IOPMAssertionRelease(inhibitor.assertion)
+		inhibitor.active = false
+	}
+}
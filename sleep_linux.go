@@ -0,0 +1,61 @@
+//go:build linux
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// systemd-inhibit holds the lock for as long as the command it runs is alive
+type systemdInhibitor struct {
+	cmd *exec.Cmd
+}
+
+func newSleepInhibitor() sleepInhibitor {
+	return &systemdInhibitor{}
+}
+
+func (inhibitor *systemdInhibitor) Inhibit() error {
+	if inhibitor.cmd != nil {
+		return nil
+	}
+
+	cmd := exec.Command("systemd-inhibit", "--what=sleep:idle", "--who=RadioSpiral Player",
+		"--why=Playing the radio", "--mode=block", "sleep", "infinity")
+	// In its own group, so we can get rid of the sleep along with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	inhibitor.cmd = cmd
+	return nil
+}
+
+func (inhibitor *systemdInhibitor) Release() {
+	if inhibitor.cmd == nil {
+		return
+	}
+
+	syscall.Kill(-inhibitor.cmd.Process.Pid, syscall.SIGTERM)
+	inhibitor.cmd.Wait()
+	inhibitor.cmd = nil
+}
@@ -0,0 +1,77 @@
+//go:build windows
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+)
+
+const ES_CONTINUOUS = 0x80000000
+const ES_SYSTEM_REQUIRED = 0x00000001
+
+var procSetThreadExecutionState = syscall.NewLazyDLL("kernel32.dll").NewProc("SetThreadExecutionState")
+
+// The execution state belongs to a thread, so we keep one locked for as long
+// as we want the system awake
+type windowsInhibitor struct {
+	release chan bool
+}
+
+func newSleepInhibitor() sleepInhibitor {
+	return &windowsInhibitor{}
+}
+
+func (inhibitor *windowsInhibitor) Inhibit() error {
+	if inhibitor.release != nil {
+		return nil
+	}
+
+	release := make(chan bool)
+	started := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		result, _, err := procSetThreadExecutionState.Call(ES_CONTINUOUS | ES_SYSTEM_REQUIRED)
+		if result == 0 {
+			started <- err
+			return
+		}
+		started <- nil
+
+		<-release
+		procSetThreadExecutionState.Call(ES_CONTINUOUS)
+	}()
+
+	err := <-started
+	if err != nil {
+		return err
+	}
+	inhibitor.release = release
+	return nil
+}
+
+func (inhibitor *windowsInhibitor) Release() {
+	if inhibitor.release != nil {
+		close(inhibitor.release)
+		inhibitor.release = nil
+	}
+}
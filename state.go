@@ -0,0 +1,101 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * What the player is doing, for anything outside the UI that needs to know:
+ * the control API, the media integrations... main keeps it up to date each
+ * time something changes, and readers always get a consistent snapshot.
+ */
+
+import (
+	"sync"
+)
+
+type CurrentTrackInfo struct {
+	Station string
+	// The stream title as we show it
+	Title string
+	// What the station API says about the track
+	Song SongInfo
+}
+
+type PlayerState struct {
+	mutex  sync.RWMutex
+	status int
+	track  CurrentTrackInfo
+	volume float64
+	// Codec, bitrate and sample rate of the stream, empty until known
+	format string
+}
+
+func NewPlayerState() *PlayerState {
+	return &PlayerState{status: Stopped}
+}
+
+func (state *PlayerState) Update(status int, track CurrentTrackInfo, volume float64) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.status = status
+	state.track = track
+	state.volume = volume
+}
+
+func (state *PlayerState) SetFormat(format string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.format = format
+}
+
+func (state *PlayerState) Format() string {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+	return state.format
+}
+
+// One of Loading, Playing, Stopped or Paused
+func (state *PlayerState) State() int {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+	return state.status
+}
+
+func (state *PlayerState) CurrentTrack() CurrentTrackInfo {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+	return state.track
+}
+
+func (state *PlayerState) Volume() float64 {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+	return state.volume
+}
+
+// All of it at once, as the control API reports it
+func (state *PlayerState) ControlStatus() ControlStatus {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+	return ControlStatus{
+		Status:  statusName(state.status),
+		Station: state.track.Station,
+		Title:   state.track.Title,
+		Volume:  state.volume,
+		Format:  state.format,
+	}
+}
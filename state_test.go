@@ -0,0 +1,75 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Meant for go test -race: the accessors are hammered from several goroutines
+// at once, and every snapshot must be one of the updates, not a mix of two
+func TestPlayerStateConcurrentAccess(t *testing.T) {
+	state := NewPlayerState()
+	statuses := []int{Loading, Playing, Paused, Stopped}
+	var workers sync.WaitGroup
+
+	for writer := 0; writer < 4; writer++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := 0; i < 500; i++ {
+				volume := float64(i%100) / 100
+				state.Update(statuses[i%len(statuses)], CurrentTrackInfo{
+					Station: "RadioSpiral",
+					Title:   fmt.Sprintf("%.2f", volume),
+				}, volume)
+				state.SetFormat(fmt.Sprintf("mp3 %d kb/s", i))
+			}
+		}()
+	}
+
+	mixed := make(chan string, 4)
+	for reader := 0; reader < 4; reader++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := 0; i < 500; i++ {
+				state.State()
+				state.CurrentTrack()
+				state.Volume()
+				state.Format()
+				status := state.ControlStatus()
+				if status.Title != "" && status.Title != fmt.Sprintf("%.2f", status.Volume) {
+					select {
+					case mixed <- fmt.Sprintf("title %s with volume %.2f", status.Title, status.Volume):
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	workers.Wait()
+	close(mixed)
+	for err := range mixed {
+		t.Errorf("mixed snapshot: %s", err)
+	}
+}
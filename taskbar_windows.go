@@ -0,0 +1,51 @@
+//go:build windows
+
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * On Windows the taskbar button shows the window icon, so we use it to show the
+ * current art at a glance. The track is in the window title, on every platform.
+ */
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"log"
+
+	"fyne.io/fyne/v2"
+)
+
+// Longest track title we put in the taskbar tooltip
+func updateTaskbar(window fyne.Window, art image.Image) {
+	if art == nil {
+		window.SetIcon(resourceIconPng)
+		return
+	}
+
+	var encoded bytes.Buffer
+	err := png.Encode(&encoded, art)
+	if err != nil {
+		log.Printf("Couldn't use the art as taskbar icon: %s", err)
+		window.SetIcon(resourceIconPng)
+		return
+	}
+	window.SetIcon(fyne.NewStaticResource("art.png", encoded.Bytes()))
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Besides the sink we hear, the decoded audio can go to other sinks at the same
+ * time (a file, a pipe to something that sends it over the network...). Each of
+ * them gets its own queue, and a sink that can't keep up loses audio instead of
+ * holding up the one we are listening to.
+ */
+
+import (
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How many reads of audio a sink can fall behind before we start dropping them
+const TEE_QUEUE_LENGTH = 64
+
+// Bytes in a frame of our 16 bit audio, one sample per channel
+const FRAME_SIZE = CHANNEL_COUNT * 2
+
+// The audio for one of the extra sinks, read from it like from ffmpeg
+type teeQueue struct {
+	mutex  sync.Mutex
+	chunks chan []byte
+	closed bool
+	// What's left of the chunk being read
+	pending []byte
+	// Bytes to take from the next chunk so that, after dropping some, the
+	// sink still gets whole frames
+	misalign int
+	dropped  int
+}
+
+func newTeeQueue() *teeQueue {
+	return &teeQueue{chunks: make(chan []byte, TEE_QUEUE_LENGTH)}
+}
+
+// Queues a copy of the audio, or drops it if the sink is too far behind.
+// Never blocks.
+func (queue *teeQueue) Push(data []byte) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if queue.closed {
+		return
+	}
+
+	skip := min(queue.misalign, len(data))
+	queue.misalign -= skip
+	data = data[skip:]
+	if len(data) == 0 {
+		return
+	}
+
+	select {
+	case queue.chunks <- append([]byte(nil), data...):
+	default:
+		if queue.dropped == 0 {
+			log.Println("An extra audio sink can't keep up, dropping audio for it")
+		}
+		queue.dropped += len(data)
+		queue.misalign = (FRAME_SIZE - len(data)%FRAME_SIZE) % FRAME_SIZE
+	}
+}
+
+func (queue *teeQueue) Read(p []byte) (int, error) {
+	if len(queue.pending) == 0 {
+		chunk, ok := <-queue.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		queue.pending = chunk
+	}
+	n := copy(p, queue.pending)
+	queue.pending = queue.pending[n:]
+	return n, nil
+}
+
+// Ends the audio for the sink once it reads what's queued
+func (queue *teeQueue) Close() error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if !queue.closed {
+		queue.closed = true
+		close(queue.chunks)
+	}
+	return nil
+}
+
+// Writes the audio as it comes to a file, raw 16 bit little endian PCM. The
+// path can be a named pipe for another program to read from.
+type FileSink struct {
+	Path string
+	// Opened with the first audio, opening a named pipe waits for its
+	// reader and we don't want to hold up the player for that
+	openOnce sync.Once
+	file     *os.File
+	// Players copying audio into the file
+	copying sync.WaitGroup
+	active  atomic.Int32
+}
+
+// Whether audio is being written to the file right now
+func (sink *FileSink) Recording() bool {
+	return sink.active.Load() > 0
+}
+
+// Waits for the audio still on its way to be written, once the players are
+// closed, and closes the file. We don't wait forever, a named pipe nobody
+// reads from would never let us finish.
+func (sink *FileSink) Finish(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		sink.copying.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Printf("Gave up waiting for the audio to be written to %s", sink.Path)
+	}
+
+	if sink.file != nil {
+		err := sink.file.Close()
+		if err != nil {
+			log.Printf("Couldn't close %s: %s", sink.Path, err)
+		}
+	}
+}
+
+func (sink *FileSink) Open(options AudioSinkOptions) error {
+	return nil
+}
+
+func (sink *FileSink) NewPlayer(reader io.Reader) SinkPlayer {
+	return &filePlayer{sink: sink, reader: reader, volume: 1.0}
+}
+
+func (sink *FileSink) writer() io.Writer {
+	sink.openOnce.Do(func() {
+		file, err := os.Create(sink.Path)
+		if err != nil {
+			log.Printf("Couldn't open %s for the audio: %s", sink.Path, err)
+			return
+		}
+		log.Printf("Writing the audio to %s", sink.Path)
+		sink.file = file
+	})
+	if sink.file == nil {
+		return io.Discard
+	}
+	return sink.file
+}
+
+type filePlayer struct {
+	mutex   sync.Mutex
+	sink    *FileSink
+	reader  io.Reader
+	volume  float64
+	started bool
+	playing bool
+}
+
+// Copies the audio until it ends, the file gets it all whatever the volume
+func (player *filePlayer) Play() {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	player.playing = true
+	if player.started {
+		return
+	}
+	player.started = true
+	player.sink.copying.Add(1)
+	player.sink.active.Add(1)
+	go func() {
+		defer player.sink.copying.Done()
+		defer player.sink.active.Add(-1)
+		_, err := io.Copy(player.sink.writer(), player.reader)
+		if err != nil {
+			log.Printf("Couldn't write the audio to %s: %s", player.sink.Path, err)
+			// Keep reading, so the queue doesn't fill up for nothing
+			io.Copy(io.Discard, player.reader)
+		}
+		player.mutex.Lock()
+		player.playing = false
+		player.mutex.Unlock()
+	}()
+}
+
+func (player *filePlayer) Pause() {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.playing = false
+}
+
+func (player *filePlayer) IsPlaying() bool {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.playing
+}
+
+func (player *filePlayer) Volume() float64 {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.volume
+}
+
+func (player *filePlayer) SetVolume(volume float64) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.volume = volume
+}
+
+func (player *filePlayer) BufferedSize() int {
+	return 0
+}
+
+func (player *filePlayer) SetBufferSize(bufferSize int) {
+}
+
+func (player *filePlayer) Close() error {
+	player.Pause()
+	return nil
+}
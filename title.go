@@ -0,0 +1,67 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Helpers to deal with the stream titles. Some stations cram all kind of stuff in
+ * there (URLs, ads...) so anything other than the card marquee should show a
+ * shortened version. Titles are UTF-8, so we always count runes, never bytes.
+ */
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const ELLIPSIS = "…"
+
+// Longest title we write to the log
+const MAX_LOG_TITLE = 120
+
+// Longest title we show in a notification
+const MAX_NOTIFICATION_TITLE = 100
+
+// Longest title we show in the window title, it also has to fit in the
+// taskbar and the window switcher
+const MAX_WINDOW_TITLE = 60
+
+const WINDOW_TITLE = "RadioSpiral Player"
+
+// Shortens the title to at most max runes, ellipsis included
+func truncateTitle(title string, max int) string {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) <= max {
+		return title
+	}
+	if max <= 0 {
+		return ""
+	}
+
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:max-1])) + ELLIPSIS
+}
+
+// The title of the window, with the track first so it can be told apart in
+// the taskbar or alt-tab
+func windowTitle(title string) string {
+	title = truncateTitle(title, MAX_WINDOW_TITLE)
+	if title == "" {
+		return WINDOW_TITLE
+	}
+	return title + " · " + WINDOW_TITLE
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Errors we can live with (no art, the station API not answering, a dropped
+ * connection we are getting back) show up as small notes at the top of the
+ * window that go away on their own, instead of a dialog that stops everything.
+ * Dialogs are for what the user has to deal with, like no ffmpeg or no audio.
+ */
+
+import (
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How long a toast stays unless dismissed
+const TOAST_DURATION = 6 * time.Second
+
+// Most toasts shown at once, the oldest go first
+const MAX_TOASTS = 3
+
+type ToastStack struct {
+	*fyne.Container
+	mutex  sync.Mutex
+	toasts []*fyne.Container
+}
+
+func NewToastStack() *ToastStack {
+	return &ToastStack{Container: container.NewVBox()}
+}
+
+// Shows the message on top of the others, until it times out or the user
+// closes it
+func (stack *ToastStack) Push(message string) {
+	queueUIUpdate(func() {
+		background := canvas.NewRectangle(theme.OverlayBackgroundColor())
+		background.StrokeColor = theme.ErrorColor()
+		background.StrokeWidth = 1
+		background.CornerRadius = theme.InputRadiusSize()
+
+		label := widget.NewLabel(message)
+		label.Wrapping = fyne.TextWrapWord
+		var toast *fyne.Container
+		closeButton := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+			stack.dismiss(toast)
+		})
+		closeButton.Importance = widget.LowImportance
+		toast = container.NewStack(background, container.NewBorder(nil, nil, nil, closeButton, label))
+
+		stack.mutex.Lock()
+		stack.toasts = append(stack.toasts, toast)
+		stack.Container.Add(toast)
+		for len(stack.toasts) > MAX_TOASTS {
+			stack.Container.Remove(stack.toasts[0])
+			stack.toasts = stack.toasts[1:]
+		}
+		stack.mutex.Unlock()
+
+		time.AfterFunc(TOAST_DURATION, func() {
+			queueUIUpdate(func() { stack.dismiss(toast) })
+		})
+	})
+}
+
+func (stack *ToastStack) dismiss(toast *fyne.Container) {
+	stack.mutex.Lock()
+	defer stack.mutex.Unlock()
+
+	for i, shown := range stack.toasts {
+		if shown == toast {
+			stack.toasts = append(stack.toasts[:i], stack.toasts[i+1:]...)
+			stack.Container.Remove(toast)
+			return
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * A stand-in for ffmpeg, to work on the player without a real stream. It writes
+ * to stderr the same kind of lines ffmpeg does (the ones the player looks for) and
+ * silence as WAV to stdout, at the same pace a real stream would.
+ *
+ * It ignores its arguments. Its behaviour can be tweaked with environment variables:
+ *
+ *   FAKE_FFMPEG_TITLE_EVERY  seconds between stream title changes (default 10)
+ *   FAKE_FFMPEG_DIE_AFTER    seconds until it exits with an error, to simulate
+ *                            a dropped connection (default never)
+ */
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const SAMPLE_RATE = 44100
+const CHANNELS = 2
+const BYTES_PER_SECOND = SAMPLE_RATE * CHANNELS * 2
+
+var TITLES = []string{
+	"Steve Roach - Structures from Silence",
+	"Robert Rich - Rainforest",
+	"Brian Eno - An Ending (Ascent)",
+}
+
+func envSeconds(name string, fallback int) time.Duration {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		value = fallback
+	}
+	return time.Duration(value) * time.Second
+}
+
+func wavHeader() []byte {
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	// Unknown length, like ffmpeg does when writing to a pipe
+	binary.LittleEndian.PutUint32(header[4:], 0xFFFFFFFF)
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], CHANNELS)
+	binary.LittleEndian.PutUint32(header[24:], SAMPLE_RATE)
+	binary.LittleEndian.PutUint32(header[28:], BYTES_PER_SECOND)
+	binary.LittleEndian.PutUint16(header[32:], CHANNELS*2)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], 0xFFFFFFFF)
+	return header
+}
+
+func main() {
+	titleEvery := envSeconds("FAKE_FFMPEG_TITLE_EVERY", 10)
+	dieAfter := envSeconds("FAKE_FFMPEG_DIE_AFTER", 0)
+
+	fmt.Fprintln(os.Stderr, "ffmpeg version fake Copyright (c) the RadioSpiral Player developers")
+	fmt.Fprintln(os.Stderr, "Input #0, mp3, from 'fake':")
+	fmt.Fprintln(os.Stderr, "  Stream #0:0: Audio: mp3, 44100 Hz, stereo, fltp, 128 kb/s")
+	fmt.Fprintln(os.Stderr, "Output #0, wav, to 'pipe:':")
+	fmt.Fprintf(os.Stderr, "    StreamTitle: %s\n", TITLES[0])
+
+	_, err := os.Stdout.Write(wavHeader())
+	if err != nil {
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	lastTitle := start
+	title := 0
+	// A tenth of a second of silence each time
+	silence := make([]byte, BYTES_PER_SECOND/10)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	for range ticker.C {
+		if _, err := os.Stdout.Write(silence); err != nil {
+			// The player went away
+			os.Exit(0)
+		}
+
+		if time.Since(lastTitle) >= titleEvery {
+			title = (title + 1) % len(TITLES)
+			fmt.Fprintf(os.Stderr, "    StreamTitle: %s\n", TITLES[title])
+			lastTitle = time.Now()
+		}
+
+		if dieAfter > 0 && time.Since(start) >= dieAfter {
+			fmt.Fprintln(os.Stderr, "[http @ 0x0] Connection reset by peer")
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Fyne doesn't have tooltips, so we roll our own. The tooltips are drawn in a
+ * layer stacked on top of the window content. We can't use the canvas overlays
+ * for this, as an overlay steals the mouse events from the widget below and the
+ * tooltip would flicker on and off as soon as it is shown.
+ */
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Layer where tooltips are drawn, it has to be stacked on top of the window content
+type TooltipLayer struct {
+	container *fyne.Container
+}
+
+func NewTooltipLayer() *TooltipLayer {
+	return &TooltipLayer{container: container.NewWithoutLayout()}
+}
+
+// Wraps the content so the tooltips can be drawn on top of it
+func (layer *TooltipLayer) Wrap(content fyne.CanvasObject) fyne.CanvasObject {
+	return container.NewStack(content, layer.container)
+}
+
+func (layer *TooltipLayer) Show(text string, owner fyne.CanvasObject) {
+	layer.Hide()
+
+	driver := fyne.CurrentApp().Driver()
+	if driver.CanvasForObject(owner) == nil {
+		return
+	}
+
+	background := canvas.NewRectangle(theme.OverlayBackgroundColor())
+	background.StrokeColor = theme.ShadowColor()
+	background.StrokeWidth = 1
+	tooltip := container.NewStack(background, widget.NewLabel(text))
+	tooltip.Resize(tooltip.MinSize())
+
+	// Below the owner, but never outside of the window
+	ownerPos := driver.AbsolutePositionForObject(owner)
+	layerPos := driver.AbsolutePositionForObject(layer.container)
+	pos := ownerPos.Subtract(layerPos).Add(fyne.NewPos(0, owner.Size().Height))
+	layerSize := layer.container.Size()
+	if pos.X+tooltip.Size().Width > layerSize.Width {
+		pos.X = layerSize.Width - tooltip.Size().Width
+	}
+	if pos.Y+tooltip.Size().Height > layerSize.Height {
+		pos.Y = ownerPos.Y - layerPos.Y - tooltip.Size().Height
+	}
+	if pos.X < 0 {
+		pos.X = 0
+	}
+	tooltip.Move(pos)
+
+	layer.container.Add(tooltip)
+}
+
+func (layer *TooltipLayer) Hide() {
+	layer.container.RemoveAll()
+}
+
+// A button that shows a tooltip when the mouse is over it
+type TooltipButton struct {
+	widget.Button
+	tooltip string
+	layer   *TooltipLayer
+	hovered bool
+}
+
+func NewTooltipButton(layer *TooltipLayer, icon fyne.Resource, tooltip string, tapped func()) *TooltipButton {
+	button := &TooltipButton{tooltip: tooltip, layer: layer}
+	button.Icon = icon
+	button.OnTapped = tapped
+	button.ExtendBaseWidget(button)
+	return button
+}
+
+func (button *TooltipButton) SetTooltip(tooltip string) {
+	button.tooltip = tooltip
+	if button.hovered {
+		button.layer.Show(button.tooltip, button)
+	}
+}
+
+func (button *TooltipButton) MouseIn(event *desktop.MouseEvent) {
+	button.Button.MouseIn(event)
+	button.hovered = true
+	button.layer.Show(button.tooltip, button)
+}
+
+func (button *TooltipButton) MouseOut() {
+	button.Button.MouseOut()
+	button.hovered = false
+	button.layer.Hide()
+}
+
+func (button *TooltipButton) Tapped(event *fyne.PointEvent) {
+	button.layer.Hide()
+	button.Button.Tapped(event)
+}
+
+// A label that shows a tooltip when the mouse is over it
+type TooltipLabel struct {
+	widget.Label
+	tooltip string
+	layer   *TooltipLayer
+	hovered bool
+}
+
+func NewTooltipLabel(layer *TooltipLayer, text string, tooltip string) *TooltipLabel {
+	label := &TooltipLabel{tooltip: tooltip, layer: layer}
+	label.Text = text
+	label.ExtendBaseWidget(label)
+	return label
+}
+
+func (label *TooltipLabel) SetTooltip(tooltip string) {
+	label.tooltip = tooltip
+	if label.hovered {
+		label.layer.Show(label.tooltip, label)
+	}
+}
+
+func (label *TooltipLabel) MouseIn(event *desktop.MouseEvent) {
+	label.hovered = true
+	if label.tooltip != "" {
+		label.layer.Show(label.tooltip, label)
+	}
+}
+
+func (label *TooltipLabel) MouseMoved(event *desktop.MouseEvent) {}
+
+func (label *TooltipLabel) MouseOut() {
+	label.hovered = false
+	label.layer.Hide()
+}
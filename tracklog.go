@@ -0,0 +1,90 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * A CSV file with every track played, kept across sessions, for hosts putting
+ * together playlists or royalty reports. Each track is written and flushed as
+ * soon as we know about it, so nothing is lost if the player goes down.
+ */
+
+import (
+	"encoding/csv"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var TRACK_LOG_HEADER = []string{"time", "artist", "title", "album", "live", "stream_title"}
+
+type TrackLog struct {
+	mutex  sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+	// The last track written, the station tells us about the same one many
+	// times while it plays
+	last []string
+}
+
+// Opens the log to add to it, creating it with its header if it's new
+func OpenTrackLog(path string) (*TrackLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	trackLog := &TrackLog{file: file, writer: csv.NewWriter(file)}
+	if info.Size() == 0 {
+		trackLog.writer.Write(TRACK_LOG_HEADER)
+		trackLog.writer.Flush()
+		if err := trackLog.writer.Error(); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return trackLog, nil
+}
+
+// Adds the track, unless it's the same as the last one
+func (trackLog *TrackLog) Log(playedAt time.Time, song SongInfo, live bool, streamTitle string) error {
+	trackLog.mutex.Lock()
+	defer trackLog.mutex.Unlock()
+
+	track := []string{song.Artist, song.Title, song.Album, strconv.FormatBool(live), streamTitle}
+	if slices.Equal(track, trackLog.last) {
+		return nil
+	}
+	trackLog.last = track
+
+	trackLog.writer.Write(append([]string{playedAt.Format(time.RFC3339)}, track...))
+	trackLog.writer.Flush()
+	return trackLog.writer.Error()
+}
+
+func (trackLog *TrackLog) Close() error {
+	trackLog.mutex.Lock()
+	defer trackLog.mutex.Unlock()
+	return trackLog.file.Close()
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Fyne setters are fine to call from any goroutine, but swapping content or
+ * showing dialogs from several goroutines at once can leave the window half
+ * drawn. Fyne 2.5 gives us no way to run code in its own main goroutine (that's
+ * fyne.Do, from 2.6 on), so those updates go through queueUIUpdate instead. It
+ * runs them one at a time, in the order they came, in a goroutine of our own:
+ * that keeps our updates from stepping on each other, not on Fyne's rendering.
+ * Once we move to Fyne 2.6 queueUIUpdate becomes a call to fyne.Do.
+ */
+
+import (
+	"sync"
+)
+
+var uiQueue struct {
+	mutex   sync.Mutex
+	pending []func()
+	// Tells the goroutine running the updates there are more
+	wake chan struct{}
+	once sync.Once
+}
+
+// Queues the update to the widgets, it runs after the ones queued before it.
+// It never waits, whoever queues it (the pollers, the player) goes on right
+// away however many updates are waiting.
+//
+// TODO: Not on Fyne's main goroutine yet, switch to fyne.Do with Fyne 2.6
+func queueUIUpdate(update func()) {
+	uiQueue.once.Do(func() {
+		uiQueue.wake = make(chan struct{}, 1)
+		go runUIUpdates()
+	})
+
+	uiQueue.mutex.Lock()
+	uiQueue.pending = append(uiQueue.pending, update)
+	uiQueue.mutex.Unlock()
+
+	select {
+	case uiQueue.wake <- struct{}{}:
+	default:
+		// Already woken, it will find this one too
+	}
+}
+
+func runUIUpdates() {
+	for range uiQueue.wake {
+		uiQueue.mutex.Lock()
+		updates := uiQueue.pending
+		uiQueue.pending = nil
+		uiQueue.mutex.Unlock()
+
+		for _, update := range updates {
+			update()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * How much we download in the session, for those on metered connections. The
+ * station API and the album art go through our HTTP clients, which count what
+ * they read. ffmpeg downloads the stream on its own, so for that one we go by
+ * its bitrate and how long we have been playing.
+ */
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+type NetworkUsage struct {
+	// Bytes downloaded, the stream ones are an estimate
+	Stream atomic.Int64
+	API    atomic.Int64
+	Art    atomic.Int64
+}
+
+var networkUsage NetworkUsage
+
+// Counts what the responses download
+type countingTransport struct {
+	counter *atomic.Int64
+}
+
+func (transport *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, counter: transport.counter}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	counter *atomic.Int64
+}
+
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.counter.Add(int64(n))
+	return n, err
+}
+
+// Adds a second of the stream at the bitrate in kb/s
+func (usage *NetworkUsage) AddStreamSecond(bitrate int) {
+	usage.Stream.Add(int64(bitrate) * 1000 / 8)
+}
+
+func (usage *NetworkUsage) Summary(bitrate int) string {
+	var summary strings.Builder
+	if bitrate > 0 {
+		fmt.Fprintf(&summary, "Stream: about %s (%d kb/s)\n", formatBytes(usage.Stream.Load()), bitrate)
+	} else {
+		fmt.Fprintf(&summary, "Stream: about %s (bitrate unknown)\n", formatBytes(usage.Stream.Load()))
+	}
+	fmt.Fprintf(&summary, "Album art: %s\n", formatBytes(usage.Art.Load()))
+	fmt.Fprintf(&summary, "Station info: %s\n", formatBytes(usage.API.Load()))
+	total := usage.Stream.Load() + usage.Art.Load() + usage.API.Load()
+	fmt.Fprintf(&summary, "Total: about %s", formatBytes(total))
+	return summary.String()
+}
+
+func formatBytes(bytes int64) string {
+	switch {
+	case bytes >= 1000*1000*1000:
+		return fmt.Sprintf("%.2f GB", float64(bytes)/(1000*1000*1000))
+	case bytes >= 1000*1000:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1000*1000))
+	case bytes >= 1000:
+		return fmt.Sprintf("%.0f KB", float64(bytes)/1000)
+	}
+	return fmt.Sprintf("%d bytes", bytes)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2023 José Carlos Cuevas
+ *
+ * This file is part of RadioSpiral Player.
+ * RadioSpiral Player is free software: you can redistribute it and/or modify it under the
+ * terms of the GNU General Public License as published by the Free Software Foundation,
+ * either version 3 of the License, or (at your option) any later version.
+ *
+ * RadioSpiral Player is distributed in the hope that it will be useful, but WITHOUT ANY
+ * WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+ * PARTICULAR PURPOSE. See the GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along with
+ * RadioSpiral Player. If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+/*
+ * Welcome dialog for the first run, so new users know what they are looking at
+ * and whether they are missing ffmpeg before they press play.
+ */
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const WELCOME_TEXT = "RadioSpiral is a community internet radio playing ambient, space and " +
+	"electronic music. Pick a station and press play to listen, the card shows what's on."
+
+// Shows the welcome dialog unless the user has already seen it
+func showWelcomeDialog(window fyne.Window, prefs fyne.Preferences, playerCmd string) {
+	if prefs.Bool(PREF_SEEN_WELCOME) {
+		return
+	}
+
+	intro := widget.NewLabel(WELCOME_TEXT)
+	intro.Wrapping = fyne.TextWrapWord
+
+	var ffmpegStatus *widget.Label
+	err := checkPlayerAvailable(playerCmd)
+	if err != nil {
+		ffmpegStatus = widget.NewLabel(fmt.Sprintf("%s. Install it from https://ffmpeg.org and restart the player.", err))
+		ffmpegStatus.Importance = widget.DangerImportance
+	} else {
+		ffmpegStatus = widget.NewLabel("ffmpeg is installed, you're all set.")
+		ffmpegStatus.Importance = widget.SuccessImportance
+	}
+	ffmpegStatus.Wrapping = fyne.TextWrapWord
+
+	autoplayCheck := widget.NewCheck("Start playing when the app opens", nil)
+	autoplayCheck.SetChecked(prefs.Bool(PREF_AUTOPLAY))
+	notificationsCheck := widget.NewCheck("Notify me when the track changes", nil)
+	notificationsCheck.SetChecked(prefs.Bool(PREF_NOTIFICATIONS))
+
+	content := container.NewVBox(intro, ffmpegStatus, widget.NewSeparator(), autoplayCheck, notificationsCheck)
+	welcome := dialog.NewCustom("Welcome to RadioSpiral Player", "Get started", content, window)
+	welcome.SetOnClosed(func() {
+		prefs.SetBool(PREF_AUTOPLAY, autoplayCheck.Checked)
+		prefs.SetBool(PREF_NOTIFICATIONS, notificationsCheck.Checked)
+		prefs.SetBool(PREF_SEEN_WELCOME, true)
+	})
+	welcome.Resize(fyne.NewSize(360, 0))
+	welcome.Show()
+}